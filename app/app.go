@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type Hook struct {
+	Name string
+	Func func(ctx context.Context) errors.Error
+
+	startupHooks int // for shutdown hooks, the number of startup hooks added before this one
+}
+
+// App runs a service: it sets up the global loggers, runs startup hooks, serves HTTP until SIGINT or SIGTERM, then
+// drains the servers and runs shutdown hooks. Every phase is logged through the Config logger.
+type App struct {
+	Name            string
+	ShutdownTimeout time.Duration // how long servers get to finish in-flight requests, and hooks get after that
+	DrainDelay      time.Duration // how long servers keep accepting requests after Draining is closed
+
+	ctx           context.Context
+	servers       []*http.Server
+	startupHooks  []Hook
+	shutdownHooks []Hook
+	draining      chan struct{}
+	stop          chan struct{}
+	stopOnce      sync.Once
+	ran           atomic.Bool
+}
+
+// New creates the application loggers in logDirectory and makes them the global loggers
+func New(name string, logDirectory string) *App {
+	logger, configLogger := log.GetApplicationLoggers(logDirectory, name)
+	log.SetGlobalLoggers(logger, configLogger)
+
+	return &App{
+		Name:            name,
+		ShutdownTimeout: 30 * time.Second,
+		DrainDelay:      5 * time.Second,
+		ctx:             context.WithValue(context.Background(), "logger", logger),
+		draining:        make(chan struct{}),
+		stop:            make(chan struct{}),
+	}
+}
+
+// Context is the base context of the app, carrying the General logger. Servers added without a BaseContext use it for
+// every request.
+func (a *App) Context() context.Context {
+	return a.ctx
+}
+
+func (a *App) AddServer(server *http.Server) {
+	if server.BaseContext == nil {
+		server.BaseContext = func(net.Listener) context.Context {
+			return a.ctx
+		}
+	}
+
+	a.servers = append(a.servers, server)
+}
+
+// startup hooks run in the order they were added, before any server starts
+func (a *App) OnStartup(name string, f func(ctx context.Context) errors.Error) {
+	a.startupHooks = append(a.startupHooks, Hook{Name: name, Func: f})
+}
+
+// shutdown hooks run in reverse order once the servers have stopped. If a startup hook fails, only the shutdown hooks
+// added before it was are run, so add each shutdown hook right after the startup hook it undoes.
+func (a *App) OnShutdown(name string, f func(ctx context.Context) errors.Error) {
+	a.shutdownHooks = append(a.shutdownHooks, Hook{Name: name, Func: f, startupHooks: len(a.startupHooks)})
+}
+
+// Draining is closed as soon as shutdown begins. Servers keep accepting requests for DrainDelay after that, so load
+// balancers have time to see readiness fail, e.g. through health.Checker.WatchDraining, and stop sending traffic.
+func (a *App) Draining() <-chan struct{} {
+	return a.draining
+}
+
+// Shutdown makes Run shut down as if it received a signal
+func (a *App) Shutdown() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+}
+
+// Run blocks until the app has shut down, returning the first error from startup, a server, or shutdown. An app can
+// only be run once.
+func (a *App) Run() errors.Error {
+	if !a.ran.CompareAndSwap(false, true) {
+		return errors.New("Application " + a.Name + " has already been run")
+	}
+
+	logger := log.Config.WithField("app", a.Name)
+	logger.Info("Starting application")
+
+	for i, hook := range a.startupHooks {
+		logger.WithField("hook", hook.Name).Info("Running startup hook")
+
+		err := hook.Func(a.ctx)
+		if err != nil {
+			logger.WithError(err).WithField("hook", hook.Name).Error("Startup hook failed")
+			_ = a.runShutdownHooks(logger, i)
+			return err
+		}
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(a.ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErrs := make(chan errors.Error, len(a.servers))
+	for _, server := range a.servers {
+		go a.serve(logger, server, serverErrs)
+	}
+
+	var firstErr errors.Error
+
+	select {
+	case <-signalCtx.Done():
+		logger.Info("Received shutdown signal")
+	case <-a.stop:
+		logger.Info("Shutdown requested")
+	case err := <-serverErrs:
+		logger.WithError(err).Error("Server failed, shutting down")
+		firstErr = err
+	}
+
+	close(a.draining)
+
+	if a.DrainDelay > 0 {
+		logger.WithField("delay", a.DrainDelay.String()).Info("Waiting for load balancers to stop sending requests")
+		time.Sleep(a.DrainDelay)
+	}
+
+	if err := a.shutdownServers(logger); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	if err := a.runShutdownHooks(logger, len(a.startupHooks)); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	logger.Info("Application stopped")
+	return firstErr
+}
+
+func (a *App) serve(logger log.Logger, server *http.Server, serverErrs chan<- errors.Error) {
+	logger = logger.WithField("addr", server.Addr)
+	logger.Info("Starting HTTP server")
+
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && !stderrors.Is(err, http.ErrServerClosed) {
+		serverErrs <- errors.Wrap(err, "HTTP server on "+server.Addr+" failed")
+		return
+	}
+
+	logger.Info("HTTP server stopped")
+}
+
+func (a *App) shutdownServers(logger log.Logger) errors.Error {
+	ctx, cancel := context.WithTimeout(a.ctx, a.ShutdownTimeout)
+	defer cancel()
+
+	logger.WithField("timeout", a.ShutdownTimeout.String()).Info("Draining HTTP servers")
+
+	// servers drain independently, so one that's stuck doesn't cut the others short
+	var wg sync.WaitGroup
+	serverErrs := make([]errors.Error, len(a.servers))
+	for i, server := range a.servers {
+		wg.Add(1)
+		go func(i int, server *http.Server) {
+			defer wg.Done()
+
+			err := server.Shutdown(ctx)
+			if err != nil {
+				logger.WithField("addr", server.Addr).Warn("HTTP server didn't drain in time, closing remaining connections")
+				_ = server.Close()
+				serverErrs[i] = errors.Wrap(err, "Failed to gracefully shut down HTTP server on "+server.Addr)
+			}
+		}(i, server)
+	}
+	wg.Wait()
+
+	for _, err := range serverErrs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runShutdownHooks runs the shutdown hooks added after no more than started startup hooks, which are the ones whose
+// startup hooks completed
+func (a *App) runShutdownHooks(logger log.Logger, started int) errors.Error {
+	ctx, cancel := context.WithTimeout(a.ctx, a.ShutdownTimeout)
+	defer cancel()
+
+	var firstErr errors.Error
+	for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+		hook := a.shutdownHooks[i]
+		if hook.startupHooks > started {
+			continue
+		}
+
+		logger.WithField("hook", hook.Name).Info("Running shutdown hook")
+
+		err := hook.Func(ctx)
+		if err != nil {
+			logger.WithError(err).WithField("hook", hook.Name).Error("Shutdown hook failed")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Main runs the app and exits the process with a non-zero status if it returned an error
+func (a *App) Main() {
+	if err := a.Run(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	app := New("app-test", t.TempDir())
+	app.ShutdownTimeout = time.Second
+	app.DrainDelay = 50 * time.Millisecond
+
+	drainedAt := make(chan time.Time, 1)
+	go func() {
+		<-app.Draining()
+		drainedAt <- time.Now()
+	}()
+
+	var shutdownAt time.Time
+	var events []string
+	app.OnStartup("first", func(ctx context.Context) errors.Error {
+		events = append(events, "startup first")
+		return nil
+	})
+	app.OnStartup("second", func(ctx context.Context) errors.Error {
+		events = append(events, "startup second")
+		return nil
+	})
+	app.OnShutdown("db", func(ctx context.Context) errors.Error {
+		events = append(events, "shutdown db")
+		return nil
+	})
+	app.OnShutdown("logs", func(ctx context.Context) errors.Error {
+		shutdownAt = time.Now()
+		events = append(events, "shutdown logs")
+		return nil
+	})
+
+	app.AddServer(&http.Server{Addr: "127.0.0.1:0"})
+
+	done := make(chan errors.Error)
+	go func() {
+		done <- app.Run()
+	}()
+
+	app.Shutdown()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't shut down")
+	}
+
+	select {
+	case <-app.Draining():
+	default:
+		t.Error("draining channel not closed")
+	}
+
+	assert.Equal(t, []string{"startup first", "startup second", "shutdown logs", "shutdown db"}, events)
+	assert.GreaterOrEqual(t, shutdownAt.Sub(<-drainedAt), app.DrainDelay, "servers keep serving while draining")
+}
+
+func TestStartupFailure(t *testing.T) {
+	app := New("app-test", t.TempDir())
+
+	var events []string
+	hook := func(event string, err errors.Error) func(ctx context.Context) errors.Error {
+		return func(ctx context.Context) errors.Error {
+			events = append(events, event)
+			return err
+		}
+	}
+
+	app.OnShutdown("cleanup", hook("shutdown cleanup", nil))
+	app.OnStartup("cache", hook("startup cache", nil))
+	app.OnShutdown("cache", hook("shutdown cache", nil))
+	app.OnStartup("db", hook("startup db", errors.New("failed to connect")))
+	app.OnShutdown("db", hook("shutdown db", nil))
+
+	err := app.Run()
+	assert.Equal(t, "failed to connect", err.Error())
+	assert.Equal(t, []string{"startup cache", "startup db", "shutdown cache", "shutdown cleanup"}, events)
+
+	assert.Contains(t, app.Run().Error(), "already been run")
+	assert.Len(t, events, 4)
+}
@@ -0,0 +1,311 @@
+package breaker
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"sync"
+	"time"
+)
+
+const CodeDependencyUnavailable = "dependency_unavailable"
+
+func init() {
+	errors.Register(errors.Definition{
+		Code:        CodeDependencyUnavailable,
+		HTTPStatus:  503,
+		Severity:    errors.SeverityWarning,
+		Origin:      errors.OriginThirdParty,
+		Message:     "{dependency} is currently unavailable",
+		Description: "A dependency has been failing, so requests to it are being rejected until it recovers.",
+	})
+}
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// Settings of a breaker. Zero fields are taken from DefaultSettings.
+type Settings struct {
+	Window           time.Duration // failures are counted over this rolling window
+	Buckets          int           // number of buckets the window is divided into
+	MinRequests      int           // the breaker won't open with fewer requests than this in the window
+	FailureThreshold float64       // fraction of failed requests in the window that opens the breaker
+	OpenTimeout      time.Duration // how long the breaker stays open before letting trial requests through
+	HalfOpenRequests int           // successful trial requests needed to close the breaker again
+}
+
+var DefaultSettings = Settings{
+	Window:           time.Minute,
+	Buckets:          6,
+	MinRequests:      10,
+	FailureThreshold: 0.5,
+	OpenTimeout:      30 * time.Second,
+	HalfOpenRequests: 3,
+}
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// Breaker stops calls to a dependency that keeps failing. Only internal errors count as failures, so bad input or
+// the client going away never opens it.
+type Breaker struct {
+	name     string
+	settings Settings
+	now      func() time.Time
+
+	mutex             sync.Mutex
+	state             State
+	generation        int // incremented on every transition
+	openedAt          time.Time
+	buckets           []bucket
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+}
+
+// New panics on invalid settings, since those are a programming error caught at startup
+func New(name string, settings Settings) *Breaker {
+	settings = settings.withDefaults()
+	if err := settings.validate(); err != "" {
+		panic("invalid settings for breaker " + name + ": " + err)
+	}
+
+	return &Breaker{
+		name:     name,
+		settings: settings,
+		now:      time.Now,
+		buckets:  make([]bucket, settings.Buckets),
+	}
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.Window == 0 {
+		s.Window = DefaultSettings.Window
+	}
+	if s.Buckets == 0 {
+		s.Buckets = DefaultSettings.Buckets
+	}
+	if s.MinRequests == 0 {
+		s.MinRequests = DefaultSettings.MinRequests
+	}
+	if s.FailureThreshold == 0 {
+		s.FailureThreshold = DefaultSettings.FailureThreshold
+	}
+	if s.OpenTimeout == 0 {
+		s.OpenTimeout = DefaultSettings.OpenTimeout
+	}
+	if s.HalfOpenRequests == 0 {
+		s.HalfOpenRequests = DefaultSettings.HalfOpenRequests
+	}
+
+	return s
+}
+
+func (s Settings) validate() string {
+	switch {
+	case s.Window < 0 || s.Buckets < 0 || s.MinRequests < 0 || s.OpenTimeout < 0 || s.HalfOpenRequests < 0:
+		return "negative setting"
+	case s.Window/time.Duration(s.Buckets) <= 0:
+		return "window too short for the number of buckets"
+	case s.FailureThreshold < 0 || s.FailureThreshold > 1:
+		return "failure threshold must be between 0 and 1"
+	}
+
+	return ""
+}
+
+var breakers = make(map[string]*Breaker)
+var breakersMutex sync.Mutex
+
+// Get returns the breaker for a dependency, creating it with DefaultSettings if it doesn't exist yet
+func Get(name string) *Breaker {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	if breaker, exists := breakers[name]; exists {
+		return breaker
+	}
+
+	breaker := New(name, DefaultSettings)
+	breakers[name] = breaker
+	return breaker
+}
+
+// Configure replaces the breaker for a dependency with one using the given settings
+func Configure(name string, settings Settings) *Breaker {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	breaker := New(name, settings)
+	breakers[name] = breaker
+	return breaker
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.checkOpenTimeout()
+	return b.state
+}
+
+// Execute runs operation if the breaker allows it, and otherwise returns a dependency_unavailable error without
+// calling it. A panic in operation counts as a failure.
+func (b *Breaker) Execute(ctx context.Context, operation func() errors.Error) errors.Error {
+	generation, state, allowed := b.allow()
+	if !allowed {
+		return errors.FromCode(CodeDependencyUnavailable, errors.Params{"dependency": b.name}).
+			With("breaker-state", state.String())
+	}
+
+	success := false
+	defer func() {
+		b.record(generation, success)
+	}()
+
+	err := operation()
+	success = err == nil || !err.Internal()
+	return err
+}
+
+// allow returns the generation the call was let through in, so its result isn't counted against a later state
+func (b *Breaker) allow() (int, State, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.checkOpenTimeout()
+
+	switch b.state {
+	case StateOpen:
+		return b.generation, b.state, false
+	case StateHalfOpen:
+		if b.halfOpenInFlight+b.halfOpenSuccesses >= b.settings.HalfOpenRequests {
+			return b.generation, b.state, false
+		}
+		b.halfOpenInFlight++
+	}
+
+	return b.generation, b.state, true
+}
+
+func (b *Breaker) record(generation int, success bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		b.halfOpenInFlight--
+		if !success {
+			b.transition(StateOpen)
+			return
+		}
+
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.settings.HalfOpenRequests {
+			b.transition(StateClosed)
+		}
+	case StateClosed:
+		current := b.currentBucket()
+		if success {
+			current.successes++
+			return
+		}
+		current.failures++
+
+		successes, failures := b.windowCounts()
+		total := successes + failures
+		if total >= b.settings.MinRequests && float64(failures)/float64(total) >= b.settings.FailureThreshold {
+			b.transition(StateOpen)
+		}
+	}
+}
+
+func (b *Breaker) checkOpenTimeout() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+
+	switch to {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		b.buckets = make([]bucket, len(b.buckets))
+	}
+
+	logger := log.General.WithFields(log.Fields{
+		"breaker":      b.name,
+		"breaker-from": from.String(),
+		"breaker-to":   to.String(),
+	})
+
+	if to == StateOpen {
+		logger.Warn("Circuit breaker opened")
+	} else {
+		logger.Info("Circuit breaker state changed")
+	}
+}
+
+func (b *Breaker) bucketDuration() time.Duration {
+	return b.settings.Window / time.Duration(len(b.buckets))
+}
+
+func (b *Breaker) currentBucket() *bucket {
+	bucketDuration := b.bucketDuration()
+	start := b.now().Truncate(bucketDuration)
+	current := &b.buckets[int(start.UnixNano()/int64(bucketDuration))%len(b.buckets)]
+
+	if !current.start.Equal(start) {
+		*current = bucket{start: start}
+	}
+
+	return current
+}
+
+func (b *Breaker) windowCounts() (successes int, failures int) {
+	windowStart := b.now().Add(-b.settings.Window)
+
+	for _, bucket := range b.buckets {
+		if bucket.start.After(windowStart) {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+package breaker
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+
+	breaker := New("upstream", Settings{
+		Window:           10 * time.Second,
+		Buckets:          10,
+		MinRequests:      4,
+		FailureThreshold: 0.5,
+		OpenTimeout:      5 * time.Second,
+		HalfOpenRequests: 2,
+	})
+	breaker.now = func() time.Time { return now }
+
+	fail := func() errors.Error { return errors.NewThirdPartyStatus(500, "broken") }
+	succeed := func() errors.Error { return nil }
+	badInput := func() errors.Error { return errors.NewInput("bad input") }
+
+	t.Run("Input errors don't count as failures", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_ = breaker.Execute(ctx, badInput)
+		}
+		assert.Equal(t, StateClosed, breaker.State())
+	})
+
+	t.Run("Opens once failure threshold reached", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		_ = breaker.Execute(ctx, succeed)
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, succeed)
+		assert.Equal(t, StateClosed, breaker.State())
+
+		_ = breaker.Execute(ctx, fail)
+		assert.Equal(t, StateOpen, breaker.State())
+	})
+
+	t.Run("Rejects calls while open", func(t *testing.T) {
+		called := false
+		err := breaker.Execute(ctx, func() errors.Error {
+			called = true
+			return nil
+		})
+
+		assert.False(t, called)
+		appErr, _ := errors.AsApplicationError(err)
+		assert.Equal(t, CodeDependencyUnavailable, appErr.Code)
+		assert.Equal(t, "upstream is currently unavailable", appErr.Message)
+		assert.Equal(t, "open", appErr.Details()["breaker-state"])
+	})
+
+	t.Run("Half-open failure reopens", func(t *testing.T) {
+		now = now.Add(5 * time.Second)
+		assert.Equal(t, StateHalfOpen, breaker.State())
+
+		_ = breaker.Execute(ctx, fail)
+		assert.Equal(t, StateOpen, breaker.State())
+	})
+
+	t.Run("Half-open panic reopens", func(t *testing.T) {
+		now = now.Add(5 * time.Second)
+
+		assert.Panics(t, func() {
+			_ = breaker.Execute(ctx, func() errors.Error { panic("broken") })
+		})
+		assert.Equal(t, StateOpen, breaker.State())
+		assert.Equal(t, 0, breaker.halfOpenInFlight)
+	})
+
+	t.Run("Rejects calls beyond the half-open trials", func(t *testing.T) {
+		now = now.Add(5 * time.Second)
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 2)
+		done := make(chan struct{}, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_ = breaker.Execute(ctx, func() errors.Error {
+					started <- struct{}{}
+					<-release
+					return nil
+				})
+				done <- struct{}{}
+			}()
+		}
+		<-started
+		<-started
+
+		err := breaker.Execute(ctx, succeed)
+		appErr, _ := errors.AsApplicationError(err)
+		assert.Equal(t, "half-open", appErr.Details()["breaker-state"])
+
+		close(release)
+		<-done
+		<-done
+		assert.Equal(t, StateClosed, breaker.State())
+	})
+
+	t.Run("Half-open successes close", func(t *testing.T) {
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, fail)
+		assert.Equal(t, StateOpen, breaker.State())
+		now = now.Add(5 * time.Second)
+
+		_ = breaker.Execute(ctx, succeed)
+		assert.Equal(t, StateHalfOpen, breaker.State())
+		_ = breaker.Execute(ctx, succeed)
+		assert.Equal(t, StateClosed, breaker.State())
+	})
+
+	t.Run("Old failures roll out of window", func(t *testing.T) {
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, fail)
+		_ = breaker.Execute(ctx, fail)
+
+		now = now.Add(11 * time.Second)
+		_ = breaker.Execute(ctx, fail)
+		assert.Equal(t, StateClosed, breaker.State())
+	})
+}
+
+func TestSettings(t *testing.T) {
+	t.Run("Zero fields are defaulted", func(t *testing.T) {
+		breaker := New("upstream", Settings{MinRequests: 2})
+		assert.Equal(t, 2, breaker.settings.MinRequests)
+		assert.Equal(t, DefaultSettings.Window, breaker.settings.Window)
+		assert.Equal(t, DefaultSettings.HalfOpenRequests, breaker.settings.HalfOpenRequests)
+
+		assert.Nil(t, breaker.Execute(context.Background(), func() errors.Error { return nil }))
+	})
+
+	t.Run("Invalid settings panic", func(t *testing.T) {
+		assert.Panics(t, func() { New("upstream", Settings{FailureThreshold: 1.5}) })
+		assert.Panics(t, func() { New("upstream", Settings{Window: time.Nanosecond, Buckets: 10}) })
+		assert.Panics(t, func() { New("upstream", Settings{HalfOpenRequests: -1}) })
+	})
+}
@@ -0,0 +1,219 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Definition documents one error code a service can return. Message is the public message template, with {name}
+// placeholders filled in from the params passed to FromCode.
+type Definition struct {
+	Code        string
+	HTTPStatus  int
+	Severity    Severity
+	Origin      Origin
+	Message     string
+	Description string
+	DocURL      string
+}
+
+type Params map[string]interface{}
+
+type Catalog struct {
+	mutex       sync.RWMutex
+	definitions map[string]Definition
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		definitions: make(map[string]Definition),
+	}
+}
+
+var DefaultCatalog = NewCatalog()
+
+func init() {
+	DefaultCatalog.Register(
+		Definition{
+			Code:        CodeCanceled,
+			HTTPStatus:  499,
+			Severity:    SeverityInfo,
+			Origin:      OriginClient,
+			Message:     "The request was canceled",
+			Description: "The client closed the connection before the response was written.",
+		},
+		Definition{
+			Code:        CodeTimeout,
+			HTTPStatus:  503,
+			Severity:    SeverityError,
+			Origin:      OriginApplication,
+			Message:     "The request timed out",
+			Description: "A deadline passed before the request completed. Returned as 504 when the timeout was in a dependency.",
+		},
+	)
+}
+
+// Register panics on duplicate codes, since that's always a programming error caught at startup
+func (c *Catalog) Register(definitions ...Definition) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, definition := range definitions {
+		if _, exists := c.definitions[definition.Code]; exists {
+			panic("error code registered twice: " + definition.Code)
+		}
+
+		c.definitions[definition.Code] = definition
+	}
+}
+
+func (c *Catalog) Lookup(code string) (Definition, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	definition, exists := c.definitions[code]
+	return definition, exists
+}
+
+// Definitions returns every registered definition, sorted by code
+func (c *Catalog) Definitions() []Definition {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	definitions := make([]Definition, 0, len(c.definitions))
+	for _, definition := range c.definitions {
+		definitions = append(definitions, definition)
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Code < definitions[j].Code
+	})
+
+	return definitions
+}
+
+type definitionJSON struct {
+	Code        string `json:"code"`
+	HTTPStatus  int    `json:"httpStatus,omitempty"`
+	Severity    string `json:"severity"`
+	Origin      string `json:"origin"`
+	Message     string `json:"message"`
+	Description string `json:"description,omitempty"`
+	DocURL      string `json:"docUrl,omitempty"`
+}
+
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	definitions := c.Definitions()
+
+	encoded := make([]definitionJSON, len(definitions))
+	for i, definition := range definitions {
+		encoded[i] = definitionJSON{
+			definition.Code,
+			definition.HTTPStatus,
+			SeverityString(definition.Severity),
+			OriginString(definition.Origin),
+			definition.Message,
+			definition.Description,
+			definition.DocURL,
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(encoded)
+}
+
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	var builder strings.Builder
+
+	builder.WriteString("| Code | HTTP status | Severity | Origin | Message | Description |\n")
+	builder.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+
+	for _, definition := range c.Definitions() {
+		code := "`" + definition.Code + "`"
+		if definition.DocURL != "" {
+			code = "[" + code + "](" + definition.DocURL + ")"
+		}
+
+		status := ""
+		if definition.HTTPStatus != 0 {
+			status = fmt.Sprint(definition.HTTPStatus)
+		}
+
+		fmt.Fprintf(&builder, "| %s | %s | %s | %s | %s | %s |\n",
+			code,
+			status,
+			SeverityString(definition.Severity),
+			OriginString(definition.Origin),
+			escapeMarkdownCell(definition.Message),
+			escapeMarkdownCell(definition.Description),
+		)
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func escapeMarkdownCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", "\\|"), "\n", " ")
+}
+
+func Register(definitions ...Definition) {
+	DefaultCatalog.Register(definitions...)
+}
+
+func Lookup(code string) (Definition, bool) {
+	return DefaultCatalog.Lookup(code)
+}
+
+// FromCode creates an error from a code registered in DefaultCatalog. An unregistered code is a bug, so it produces an
+// internal error rather than whatever the caller intended.
+func FromCode(code string, params Params) *ApplicationError {
+	return fromCode(nil, code, params)
+}
+
+// WrapCode is FromCode with an inner error
+func WrapCode(inner error, code string, params Params) *ApplicationError {
+	return fromCode(inner, code, params)
+}
+
+func fromCode(inner error, code string, params Params) *ApplicationError {
+	definition, exists := DefaultCatalog.Lookup(code)
+	if !exists {
+		err := newErrorSkip(1, SeverityError, OriginApplication, "Unregistered error code "+code, inner)
+		err.Code = code
+		return err
+	}
+
+	err := newErrorSkip(1, definition.Severity, definition.Origin, interpolate(definition.Message, params), inner)
+	err.Code = code
+	err.template = definition.Message
+	err.params = params
+	return err
+}
+
+// Template returns the message template the error was created from, if it came from the catalog
+func (e *ApplicationError) Template() string {
+	return e.template
+}
+
+func (e *ApplicationError) Params() Params {
+	return e.params
+}
+
+func interpolate(template string, params Params) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	replacements := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template)
+}
@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// Temporary reports whether the error was caused by a condition expected to clear on its own, like a timeout, a reset
+// connection or an overloaded upstream.
+func (e *ApplicationError) Temporary() bool {
+	return e.temporary
+}
+
+// Retryable reports whether repeating the failed operation may succeed. Every temporary error is retryable, as are
+// conflicts like serialization failures that aren't really about availability.
+func (e *ApplicationError) Retryable() bool {
+	return e.retryable || e.temporary
+}
+
+func (e *ApplicationError) WithTemporary(temporary bool) *ApplicationError {
+	e.temporary = temporary
+	return e
+}
+
+func (e *ApplicationError) WithRetryable(retryable bool) *ApplicationError {
+	e.retryable = retryable
+	return e
+}
+
+// postgres SQLSTATEs for serialization_failure and deadlock_detected, both of which mean "run the transaction again"
+var retryableSQLStates = map[string]bool{
+	"40001": true,
+	"40P01": true,
+}
+
+var temporaryErrnos = []error{
+	syscall.ECONNRESET,
+	syscall.ECONNREFUSED,
+	syscall.ECONNABORTED,
+	syscall.EPIPE,
+}
+
+func classifyTemporary(err error) (temporary bool, retryable bool) {
+	if err == nil {
+		return false, false
+	}
+
+	var appErr Error
+	if errors.As(err, &appErr) {
+		return appErr.Temporary(), appErr.Retryable()
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false, false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true, true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, true
+	}
+
+	for _, errno := range temporaryErrnos {
+		if errors.Is(err, errno) {
+			return true, true
+		}
+	}
+
+	// both lib/pq and pgx errors expose this
+	var sqlStateErr interface{ SQLState() string }
+	if errors.As(err, &sqlStateErr) && retryableSQLStates[sqlStateErr.SQLState()] {
+		return false, true
+	}
+
+	return false, false
+}
+
+func temporaryStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
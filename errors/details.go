@@ -0,0 +1,89 @@
+package errors
+
+import "errors"
+
+type detail struct {
+	value  interface{}
+	public bool
+}
+
+// With attaches a detail that is logged with the error but not shown to clients
+func (e *ApplicationError) With(key string, value interface{}) *ApplicationError {
+	return e.setDetail(key, value, false)
+}
+
+func (e *ApplicationError) WithDetails(details map[string]interface{}) *ApplicationError {
+	for key, value := range details {
+		e.setDetail(key, value, false)
+	}
+	return e
+}
+
+// WithPublic attaches a detail that is also included in problem responses, so it must be safe to show to clients
+func (e *ApplicationError) WithPublic(key string, value interface{}) *ApplicationError {
+	return e.setDetail(key, value, true)
+}
+
+// the QueryError versions return the QueryError, where the promoted methods would return the embedded ApplicationError
+// and lose the query
+func (e *QueryError) With(key string, value interface{}) *QueryError {
+	e.ApplicationError.With(key, value)
+	return e
+}
+
+func (e *QueryError) WithDetails(details map[string]interface{}) *QueryError {
+	e.ApplicationError.WithDetails(details)
+	return e
+}
+
+func (e *QueryError) WithPublic(key string, value interface{}) *QueryError {
+	e.ApplicationError.WithPublic(key, value)
+	return e
+}
+
+// Details returns the details of this error and everything it wraps. Details closer to the top of the chain override
+// ones with the same key further down.
+func (e *ApplicationError) Details() map[string]interface{} {
+	return e.collectDetails(false)
+}
+
+func (e *ApplicationError) PublicDetails() map[string]interface{} {
+	return e.collectDetails(true)
+}
+
+func (e *ApplicationError) setDetail(key string, value interface{}, public bool) *ApplicationError {
+	if e.details == nil {
+		e.details = make(map[string]detail)
+	}
+
+	e.details[key] = detail{value, public}
+	return e
+}
+
+func (e *ApplicationError) collectDetails(publicOnly bool) map[string]interface{} {
+	var chain []*ApplicationError
+	for err := error(e); err != nil; {
+		if appErr, isAppErr := AsApplicationError(err); isAppErr {
+			chain = append(chain, appErr)
+			err = appErr.Inner
+		} else {
+			err = errors.Unwrap(err)
+		}
+	}
+
+	var details map[string]interface{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, d := range chain[i].details {
+			if publicOnly && !d.public {
+				continue
+			}
+
+			if details == nil {
+				details = make(map[string]interface{})
+			}
+			details[key] = d.value
+		}
+	}
+
+	return details
+}
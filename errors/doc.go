@@ -0,0 +1,6 @@
+// Package errors is the error type shared by the other packages, with the severity, origin and details that decide how
+// an error is logged and answered.
+//
+// SetStackTraceOptions and SetRedactionPolicy change package-wide settings without synchronization, so call them
+// during startup, before errors are created. Register and the Catalog methods are safe to call at any time.
+package errors
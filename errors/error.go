@@ -6,36 +6,75 @@ type StackFrame struct {
 	File     string
 	FileLine int
 	Function string
+
+	// only filled in when StackTraceOptions.Enrich is set
+	Package   string `json:",omitempty"`
+	ShortFile string `json:",omitempty"` // relative to the application module, or the import path for dependencies
+	InApp     bool   `json:",omitempty"` // frame belongs to the application module rather than a dependency
 }
 
 type Severity int
 
 const (
-	SeverityError   = 0
-	SeverityWarning = 1
+	SeverityError    = 0
+	SeverityWarning  = 1
+	SeverityInfo     = 2
+	SeverityDebug    = 3
+	SeverityCritical = 4
 )
 
+func SeverityString(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityDebug:
+		return "debug"
+	case SeverityCritical:
+		return "critical"
+	}
+
+	return "unknown"
+}
+
 type Origin int
 
 const (
 	OriginInput       = 0 // cause of error is in user input
 	OriginApplication = 1 // cause of error is in this application
 	OriginThirdParty  = 2 // cause of error is in another application or API, or the interface to the aforementioned
+	OriginClient      = 3 // client went away or canceled the request before it completed
+)
+
+const (
+	CodeCanceled = "client_closed_request"
+	CodeTimeout  = "timeout"
 )
 
 type Error interface {
 	Error() string
 	Internal() bool
 	Warning() bool
+	Temporary() bool
+	Retryable() bool
 	Is(error) bool
 }
 
 type ApplicationError struct {
 	Severity   Severity
 	Origin     Origin
+	Code       string
 	Message    string
 	Inner      error
-	StackTrace []StackFrame
+	StackTrace *Stack // used to be a []StackFrame, which StackTrace.Frames() returns
+	temporary  bool
+	retryable  bool
+	details    map[string]detail
+	template   string
+	params     Params
 }
 
 func (e *ApplicationError) Error() string {
@@ -58,6 +97,20 @@ func (e *ApplicationError) Is(err error) bool {
 	return errors.Is(e.Inner, err)
 }
 
+// AsApplicationError gets at the ApplicationError of either error type in this package
+func AsApplicationError(err error) (*ApplicationError, bool) {
+	switch e := err.(type) {
+	case *ApplicationError:
+		return e, e != nil
+	case *QueryError:
+		if e != nil {
+			return &e.ApplicationError, true
+		}
+	}
+
+	return nil, false
+}
+
 type QueryError struct {
 	ApplicationError
 	Query string
@@ -72,6 +125,8 @@ func OriginString(origin Origin) string {
 		return "application"
 	case OriginThirdParty:
 		return "third-party"
+	case OriginClient:
+		return "client"
 	}
 
 	return "unknown"
@@ -3,114 +3,87 @@ package errors
 import (
 	"context"
 	"errors"
-	"runtime"
 )
 
 // generally assume errors are application and error severity, and use other functions for when that's not the case
 
 func Wrap(err error, message string) *ApplicationError {
-	return &ApplicationError{
-		SeverityError,
-		OriginApplication,
-		message,
-		err,
-		stackTrace(),
-	}
+	return newError(SeverityError, OriginApplication, message, err)
 }
 
 func New(message string) *ApplicationError {
-	return &ApplicationError{
-		SeverityError,
-		OriginApplication,
-		message,
-		nil,
-		stackTrace(),
-	}
+	return newError(SeverityError, OriginApplication, message, nil)
 }
 
 func NewInput(message string) *ApplicationError {
-	return &ApplicationError{
-		SeverityWarning,
-		OriginInput,
-		message,
-		nil,
-		stackTrace(),
-	}
+	return newError(SeverityWarning, OriginInput, message, nil)
 }
 
 func WrapDBError(err error, message string) *ApplicationError {
-	severity := SeverityError
-	if errors.Is(err, context.Canceled) {
-		severity = SeverityWarning
-	}
-
-	return &ApplicationError{
-		Severity(severity),
-		OriginThirdParty,
-		message,
-		err,
-		stackTrace(),
-	}
+	return newError(SeverityError, OriginThirdParty, message, err)
 }
 
 func WrapQueryError(err error, message string, query string, args ...interface{}) *QueryError {
-	severity := SeverityError
-	if errors.Is(err, context.Canceled) {
-		severity = SeverityWarning
-	}
-
 	return &QueryError{
-		ApplicationError{
-			Severity(severity),
-			OriginThirdParty,
-			message,
-			err,
-			stackTrace(),
-		},
+		*newError(SeverityError, OriginThirdParty, message, err),
 		query,
 		args,
 	}
 }
 
-func WrapInputError(err error, message string) *ApplicationError {
-	return &ApplicationError{
-		SeverityError,
-		OriginInput,
-		message,
-		err,
-		stackTrace(),
-	}
+func WrapThirdParty(err error, message string) *ApplicationError {
+	return newError(SeverityError, OriginThirdParty, message, err)
 }
 
-func stackTrace() []StackFrame {
-	pcs := make([]uintptr, 100)
+// NewThirdPartyStatus is for an API we called responding with an unsuccessful HTTP status
+func NewThirdPartyStatus(statusCode int, message string) *ApplicationError {
+	err := newError(SeverityError, OriginThirdParty, message, nil)
+	err.temporary = temporaryStatus(statusCode)
+	return err
+}
 
-	n := runtime.Callers(3, pcs) // skip Callers itself, stackTrace, and the caller in this file
+func WrapInputError(err error, message string) *ApplicationError {
+	return newError(SeverityError, OriginInput, message, err)
+}
 
-	if n == 0 {
-		return nil
-	}
+// WrapClientCanceled is for work that stopped because the client went away. Only use it when the request's own context
+// was canceled, since a cancellation from anywhere else is a failure like any other.
+func WrapClientCanceled(err error, message string) *ApplicationError {
+	appErr := newError(SeverityInfo, OriginClient, message, err)
+	appErr.Code = CodeCanceled
+	return appErr
+}
 
-	frames := make([]StackFrame, 0, n)
-	callersFrames := runtime.CallersFrames(pcs)
+// every constructor in this package must call this directly, so that the stack capture skips the right number of frames
+func newError(severity Severity, origin Origin, message string, inner error) *ApplicationError {
+	return newErrorSkip(1, severity, origin, message, inner)
+}
 
-	if callersFrames == nil {
-		return nil
+// newErrorSkip is newError for constructors that share a helper, with skip being the number of frames in this package
+// between the constructor and newErrorSkip
+func newErrorSkip(skip int, severity Severity, origin Origin, message string, inner error) *ApplicationError {
+	err := &ApplicationError{
+		Severity:   severity,
+		Origin:     origin,
+		Message:    message,
+		Inner:      inner,
+		StackTrace: captureStack(origin, skip),
 	}
 
-	for {
-		frame, more := callersFrames.Next()
+	if origin != OriginInput {
+		err.temporary, err.retryable = classifyTemporary(inner)
+	}
 
-		frames = append(frames, StackFrame{
-			frame.File,
-			frame.Line,
-			frame.Function,
-		})
+	// wrapping doesn't change what kind of error it is
+	if innerAppErr, isAppErr := AsApplicationError(inner); isAppErr {
+		err.Code = innerAppErr.Code
+	}
 
-		if !more {
-			break
-		}
+	// a deadline is the same timeout wherever it surfaces. Cancellation isn't classified here, since only the caller
+	// knows whether it was the client going away or something internal like a canceled sibling.
+	if errors.Is(inner, context.DeadlineExceeded) {
+		err.Code = CodeTimeout
 	}
 
-	return frames
+	return err
 }
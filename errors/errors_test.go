@@ -0,0 +1,435 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStackTrace(t *testing.T) {
+	t.Run("First frame is caller of constructor", func(t *testing.T) {
+		err := New("test")
+		frames := err.StackTrace.Frames()
+		assert.NotEmpty(t, frames)
+		assert.True(t, strings.HasSuffix(frames[0].Function, "TestStackTrace.func1"), frames[0].Function)
+	})
+
+	t.Run("Runtime and standard library frames filtered", func(t *testing.T) {
+		err := New("test")
+		for _, frame := range err.StackTrace.Frames() {
+			assert.False(t, strings.HasPrefix(frame.Function, "runtime."), frame.Function)
+			assert.False(t, strings.HasPrefix(frame.Function, "testing."), frame.Function)
+		}
+	})
+
+	t.Run("Input errors skipped when configured", func(t *testing.T) {
+		defer SetStackTraceOptions(stackTraceOptions)
+		SetStackTraceOptions(StackTraceOptions{MaxDepth: 32, FilterRuntime: true, SkipInput: true})
+
+		assert.Nil(t, NewInput("test").StackTrace)
+		assert.Nil(t, NewInput("test").StackTrace.Frames())
+		assert.NotNil(t, New("test").StackTrace)
+	})
+
+	t.Run("Depth limited", func(t *testing.T) {
+		defer SetStackTraceOptions(stackTraceOptions)
+		SetStackTraceOptions(StackTraceOptions{MaxDepth: 1, FilterRuntime: false})
+
+		frames := New("test").StackTrace.Frames()
+		assert.Len(t, frames, 1)
+	})
+}
+
+func TestIsStandardLibraryFrame(t *testing.T) {
+	root := standardLibraryRoot()
+	assert.NotEmpty(t, root)
+
+	assert.True(t, isStandardLibraryFrame("runtime.goexit", root+"runtime/asm_amd64.s"))
+	assert.True(t, isStandardLibraryFrame("net/http.(*conn).serve", root+"net/http/server.go"))
+	assert.False(t, isStandardLibraryFrame("main.main", "/home/me/myapp/main.go"))
+	assert.False(t, isStandardLibraryFrame("internal/svc.Run", "/home/me/myapp/internal/svc/svc.go"))
+	assert.False(t, isStandardLibraryFrame("github.com/sjohna/go-server-common/errors.New", "/src/go-server-common/errors/errors.go"))
+
+	// without a source directory to go by, modules of the build are kept even without a domain
+	modules := []string{"myapp", "github.com/sjohna/go-server-common"}
+	assert.True(t, isStandardLibraryPackage("net/http", modules))
+	assert.False(t, isStandardLibraryPackage("main", modules))
+	assert.False(t, isStandardLibraryPackage("myapp/internal/svc", modules))
+	assert.False(t, isStandardLibraryPackage("github.com/other/lib", modules))
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("Round trip of wrapped chain", func(t *testing.T) {
+		inner := WrapQueryError(fmt.Errorf("connection refused"), "Error running Select", "SELECT 1 WHERE a = $1", "a")
+		inner.Code = "db_unavailable"
+		outer := Wrap(inner, "Failed to load thing")
+
+		data, err := json.Marshal(outer)
+		assert.NoError(t, err)
+
+		decoded, err := FromJSON(data)
+		assert.NoError(t, err)
+
+		appErr, isAppErr := decoded.(*ApplicationError)
+		assert.True(t, isAppErr)
+		assert.Equal(t, "Failed to load thing", appErr.Message)
+		assert.Equal(t, SeverityError, int(appErr.Severity))
+		assert.Equal(t, OriginApplication, int(appErr.Origin))
+		assert.Equal(t, outer.StackTrace.Frames(), appErr.StackTrace.Frames())
+
+		queryErr, isQueryErr := appErr.Inner.(*QueryError)
+		assert.True(t, isQueryErr)
+		assert.Equal(t, "db_unavailable", queryErr.Code)
+		assert.Equal(t, OriginThirdParty, int(queryErr.Origin))
+		assert.Equal(t, "SELECT 1 WHERE a = $1", queryErr.Query)
+		assert.Equal(t, []interface{}{"a"}, queryErr.Args)
+		assert.Equal(t, "connection refused", queryErr.Inner.Error())
+	})
+
+	t.Run("Unmarshal into QueryError", func(t *testing.T) {
+		data, err := json.Marshal(WrapQueryError(nil, "query error", "SELECT 1"))
+		assert.NoError(t, err)
+
+		var queryErr QueryError
+		assert.NoError(t, json.Unmarshal(data, &queryErr))
+		assert.Equal(t, "query error", queryErr.Message)
+		assert.Equal(t, "SELECT 1", queryErr.Query)
+		assert.Nil(t, queryErr.Inner)
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	newErr := func(message string) *ApplicationError {
+		return New(message)
+	}
+
+	t.Run("Same message and origin share fingerprint", func(t *testing.T) {
+		assert.Equal(t, newErr("test").Fingerprint(), newErr("test").Fingerprint())
+	})
+
+	t.Run("Different message or code changes fingerprint", func(t *testing.T) {
+		withCode := newErr("test")
+		withCode.Code = "code"
+		assert.NotEqual(t, newErr("test").Fingerprint(), newErr("other").Fingerprint())
+		assert.NotEqual(t, newErr("test").Fingerprint(), withCode.Fingerprint())
+	})
+
+	t.Run("Different call site changes fingerprint", func(t *testing.T) {
+		assert.NotEqual(t, newErr("test").Fingerprint(), New("test").Fingerprint())
+	})
+
+	t.Run("Different root cause changes fingerprint", func(t *testing.T) {
+		wrapErr := func(inner error) *ApplicationError {
+			return Wrap(inner, "test")
+		}
+
+		assert.Equal(t, wrapErr(sqlStateError("23505")).Fingerprint(), wrapErr(sqlStateError("23505")).Fingerprint())
+		assert.NotEqual(t, wrapErr(sqlStateError("23505")).Fingerprint(), wrapErr(sqlStateError("40001")).Fingerprint())
+		assert.NotEqual(t, wrapErr(sqlStateError("23505")).Fingerprint(), wrapErr(fmt.Errorf("23505")).Fingerprint())
+
+		withCode := NewInput("inner")
+		withCode.Code = "code"
+		assert.NotEqual(t, wrapErr(NewInput("inner")).Fingerprint(), wrapErr(withCode).Fingerprint())
+	})
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "sql error " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+func TestRetryableClassification(t *testing.T) {
+	t.Run("Timeouts are temporary", func(t *testing.T) {
+		err := WrapDBError(context.DeadlineExceeded, "timed out")
+		assert.True(t, err.Temporary())
+		assert.True(t, err.Retryable())
+	})
+
+	t.Run("Serialization failures are retryable but not temporary", func(t *testing.T) {
+		err := WrapQueryError(sqlStateError("40001"), "serialization failure", "SELECT 1")
+		assert.False(t, err.Temporary())
+		assert.True(t, err.Retryable())
+	})
+
+	t.Run("Classification inherited through wrapping", func(t *testing.T) {
+		err := Wrap(NewThirdPartyStatus(503, "unavailable"), "outer")
+		assert.True(t, err.Temporary())
+		assert.False(t, Wrap(NewThirdPartyStatus(500, "broken"), "outer").Retryable())
+	})
+
+	t.Run("Cancellation and input errors not retryable", func(t *testing.T) {
+		assert.False(t, WrapDBError(context.Canceled, "canceled").Retryable())
+		assert.False(t, WrapInputError(context.DeadlineExceeded, "bad input").Retryable())
+	})
+}
+
+func TestDetails(t *testing.T) {
+	inner := New("inner").With("userID", 1).With("entityID", 2).WithPublic("field", "name")
+	outer := Wrap(fmt.Errorf("plain: %w", inner), "outer").With("entityID", 3)
+
+	t.Run("Accumulated through chain with outer overriding", func(t *testing.T) {
+		assert.Equal(t, map[string]interface{}{"userID": 1, "entityID": 3, "field": "name"}, outer.Details())
+	})
+
+	t.Run("Only public details exposed", func(t *testing.T) {
+		assert.Equal(t, map[string]interface{}{"field": "name"}, outer.PublicDetails())
+	})
+
+	t.Run("Survive JSON round trip", func(t *testing.T) {
+		data, err := json.Marshal(inner)
+		assert.NoError(t, err)
+
+		var decoded ApplicationError
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, map[string]interface{}{"field": "name"}, decoded.PublicDetails())
+		assert.Len(t, decoded.Details(), 3)
+	})
+
+	t.Run("Query errors keep their query", func(t *testing.T) {
+		queryErr := WrapQueryError(fmt.Errorf("inner"), "query failed", "SELECT 1").With("table", "things").WithPublic("field", "id")
+		assert.Equal(t, "SELECT 1", queryErr.Query)
+		assert.Equal(t, map[string]interface{}{"table": "things", "field": "id"}, queryErr.Details())
+	})
+}
+
+func TestRedaction(t *testing.T) {
+	defer SetRedactionPolicy(RedactionPolicy{})
+
+	type user struct {
+		Email        string `db:"email"`
+		PasswordHash string `db:"password_hash"`
+		Name         string
+	}
+
+	type apiKey string
+
+	SetRedactionPolicy(RedactionPolicy{
+		Positions: []int{1},
+		Names:     []string{"password_hash"},
+		Types:     []reflect.Type{reflect.TypeOf(apiKey(""))},
+	})
+
+	t.Run("Positional args", func(t *testing.T) {
+		err := WrapQueryError(nil, "test", "SELECT", "a", "b", Sensitive("c"), apiKey("d"), 5)
+		assert.Equal(t, []interface{}{"a", Redacted, Redacted, Redacted, 5}, err.RedactedArgs())
+	})
+
+	t.Run("Named struct arg", func(t *testing.T) {
+		err := WrapQueryError(nil, "test", "INSERT", user{"a@b.c", "hash", "name"})
+		assert.Equal(t, []interface{}{map[string]interface{}{"email": "a@b.c", "password_hash": Redacted, "name": "name"}}, err.RedactedArgs())
+	})
+
+	t.Run("Named map arg", func(t *testing.T) {
+		err := WrapQueryError(nil, "test", "INSERT", map[string]interface{}{"password_hash": "hash", "key": apiKey("d")})
+		assert.Equal(t, []interface{}{map[string]interface{}{"password_hash": Redacted, "key": Redacted}}, err.RedactedArgs())
+	})
+
+	t.Run("Redacted in JSON", func(t *testing.T) {
+		data, err := json.Marshal(WrapQueryError(nil, "test", "SELECT", Sensitive("secret")))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "secret")
+	})
+}
+
+func TestContextClassification(t *testing.T) {
+	t.Run("Cancellation keeps its origin", func(t *testing.T) {
+		err := Wrap(WrapQueryError(context.Canceled, "Error running Select", "SELECT 1"), "outer")
+		assert.Equal(t, OriginApplication, int(err.Origin))
+		assert.Equal(t, SeverityError, int(err.Severity))
+		assert.Empty(t, err.Code)
+		assert.True(t, err.Internal())
+	})
+
+	t.Run("Client cancellation is a client error at info", func(t *testing.T) {
+		err := WrapClientCanceled(WrapQueryError(context.Canceled, "Error running Select", "SELECT 1"), "client went away")
+		assert.Equal(t, OriginClient, int(err.Origin))
+		assert.Equal(t, SeverityInfo, int(err.Severity))
+		assert.Equal(t, CodeCanceled, err.Code)
+		assert.False(t, err.Internal())
+		assert.Equal(t, CodeCanceled, Wrap(err, "outer").Code)
+	})
+
+	t.Run("Deadline is a timeout keeping its origin", func(t *testing.T) {
+		err := WrapDBError(fmt.Errorf("query: %w", context.DeadlineExceeded), "timed out")
+		assert.Equal(t, OriginThirdParty, int(err.Origin))
+		assert.Equal(t, CodeTimeout, err.Code)
+		assert.True(t, err.Internal())
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Register(Definition{
+		Code:       "widget_not_found",
+		HTTPStatus: 404,
+		Severity:   SeverityWarning,
+		Origin:     OriginInput,
+		Message:    "Widget {id} not found",
+		DocURL:     "https://example.com/errors#widget_not_found",
+	})
+
+	t.Run("Duplicate registration panics", func(t *testing.T) {
+		assert.Panics(t, func() {
+			catalog.Register(Definition{Code: "widget_not_found"})
+		})
+	})
+
+	t.Run("FromCode interpolates params", func(t *testing.T) {
+		defer func(catalog *Catalog) { DefaultCatalog = catalog }(DefaultCatalog)
+		DefaultCatalog = catalog
+
+		err := FromCode("widget_not_found", Params{"id": 5})
+		assert.Equal(t, "Widget 5 not found", err.Message)
+		assert.Equal(t, "widget_not_found", err.Code)
+		assert.Equal(t, OriginInput, int(err.Origin))
+		assert.Equal(t, "widget_not_found", Wrap(err, "outer").Code)
+		assert.Equal(t, FromCode("widget_not_found", Params{"id": 5}).Template(), FromCode("widget_not_found", Params{"id": 6}).Template())
+	})
+
+	t.Run("Unregistered code is internal", func(t *testing.T) {
+		assert.True(t, FromCode("not_a_code", nil).Internal())
+	})
+
+	t.Run("Stack starts at the caller", func(t *testing.T) {
+		for _, err := range []*ApplicationError{FromCode("not_a_code", nil), WrapCode(fmt.Errorf("inner"), "not_a_code", nil)} {
+			assert.True(t, strings.HasSuffix(err.StackTrace.Frames()[0].Function, "TestCatalog.func4"), err.StackTrace.Frames()[0].Function)
+		}
+	})
+
+	t.Run("Markdown export", func(t *testing.T) {
+		var builder strings.Builder
+		assert.NoError(t, catalog.WriteMarkdown(&builder))
+		assert.Contains(t, builder.String(), "| [`widget_not_found`](https://example.com/errors#widget_not_found) | 404 | warning | input | Widget {id} not found |  |\n")
+	})
+
+	t.Run("JSON export", func(t *testing.T) {
+		var builder strings.Builder
+		assert.NoError(t, catalog.WriteJSON(&builder))
+
+		var exported []map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(builder.String()), &exported))
+		assert.Equal(t, "widget_not_found", exported[0]["code"])
+		assert.Equal(t, float64(404), exported[0]["httpStatus"])
+	})
+}
+
+func TestLocalizedMessage(t *testing.T) {
+	defer func(catalog *Catalog, translations *Translations) {
+		DefaultCatalog = catalog
+		DefaultTranslations = translations
+	}(DefaultCatalog, DefaultTranslations)
+
+	DefaultCatalog = NewCatalog()
+	DefaultCatalog.Register(Definition{Code: "widget_not_found", Message: "Widget {id} not found"})
+
+	DefaultTranslations = NewTranslations()
+	assert.NoError(t, DefaultTranslations.LoadFS(fstest.MapFS{
+		"en.json": {Data: []byte(`{"widget_not_found": "Widget {id} could not be found"}`)},
+		"fr.json": {Data: []byte(`{"widget_not_found": "Widget {id} introuvable"}`)},
+	}, "*.json"))
+
+	err := Wrap(FromCode("widget_not_found", Params{"id": 5}), "Failed to load widget")
+
+	assert.Equal(t, "Widget 5 introuvable", err.LocalizedMessage("de", "fr-CA"))
+	assert.Equal(t, "Widget 5 could not be found", err.LocalizedMessage("de"))
+	assert.Equal(t, "no code", New("no code").LocalizedMessage("fr"))
+
+	DefaultTranslations = NewTranslations()
+	assert.Equal(t, "Widget 5 not found", err.LocalizedMessage("fr"))
+}
+
+func TestStackFrameEnrichment(t *testing.T) {
+	defer SetStackTraceOptions(stackTraceOptions)
+	SetStackTraceOptions(StackTraceOptions{MaxDepth: 32, FilterRuntime: false, Enrich: true, AppModule: "github.com/sjohna/go-server-common"})
+
+	frames := New("test").StackTrace.Frames()
+
+	assert.Equal(t, "github.com/sjohna/go-server-common/errors", frames[0].Package)
+	assert.Equal(t, "errors/errors_test.go", frames[0].ShortFile)
+	assert.True(t, frames[0].InApp)
+
+	last := frames[len(frames)-1]
+	assert.Equal(t, "runtime", last.Package)
+	assert.False(t, last.InApp)
+
+	appFrame, found := New("test").StackTrace.FirstAppFrame()
+	assert.True(t, found)
+	assert.Equal(t, "TestStackFrameEnrichment", appFrame.Function[strings.LastIndex(appFrame.Function, ".")+1:])
+
+	t.Run("Not looked for without enrichment", func(t *testing.T) {
+		SetStackTraceOptions(StackTraceOptions{MaxDepth: 32, FilterRuntime: false, Enrich: false, AppModule: "github.com/sjohna/go-server-common"})
+
+		stack := New("test").StackTrace
+		_, found := stack.FirstAppFrame()
+		assert.False(t, found)
+		assert.Nil(t, stack.frames, "frames aren't resolved just to look")
+	})
+}
+
+func TestGroup(t *testing.T) {
+	t.Run("No errors", func(t *testing.T) {
+		group, _ := NewGroup(context.Background())
+		group.Go(func(ctx context.Context) Error { return nil })
+		assert.Nil(t, group.Wait())
+	})
+
+	t.Run("Internal error cancels siblings", func(t *testing.T) {
+		group, ctx := NewGroup(context.Background())
+
+		group.Go(func(ctx context.Context) Error {
+			return New("failed")
+		})
+
+		group.Go(func(ctx context.Context) Error {
+			<-ctx.Done()
+			return nil
+		})
+
+		err := group.Wait()
+		assert.Equal(t, "failed", err.Error())
+		assert.Error(t, ctx.Err())
+	})
+
+	t.Run("Input error doesn't cancel siblings", func(t *testing.T) {
+		group, ctx := NewGroup(context.Background())
+
+		group.Go(func(ctx context.Context) Error {
+			return NewInput("bad input")
+		})
+
+		err := group.Wait()
+		assert.Equal(t, "bad input", err.Error())
+		assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+	})
+
+	t.Run("Panic recovered with panicking stack", func(t *testing.T) {
+		group, _ := NewGroup(context.Background())
+
+		group.Go(func(ctx context.Context) Error {
+			panic("boom")
+		})
+
+		err := group.WaitAll()
+		multiErr, isMultiErr := err.(*MultiError)
+		assert.True(t, isMultiErr)
+		assert.Len(t, multiErr.Errors, 1)
+		assert.Equal(t, "Recovered from panic: boom", multiErr.Error())
+
+		appErr, _ := AsApplicationError(multiErr.Errors[0])
+		functions := make([]string, 0)
+		for _, frame := range appErr.StackTrace.Frames() {
+			functions = append(functions, frame.Function)
+		}
+		assert.Contains(t, strings.Join(functions, "\n"), "TestGroup.func4.1")
+	})
+}
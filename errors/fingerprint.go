@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const fingerprintFrameCount = 3
+
+// Fingerprint identifies errors that are "the same" failure: same code and message raised from the same place. Errors
+// from the catalog use their message template, so differing params don't split groups, and only function names are
+// used from the stack, so line number changes between deploys don't either. The type and code of the root cause are
+// included too, so the same wrapping message over different underlying failures doesn't merge them.
+func (e *ApplicationError) Fingerprint() string {
+	hash := sha256.New()
+
+	hash.Write([]byte(e.Code))
+	hash.Write([]byte{0})
+	if e.template != "" {
+		hash.Write([]byte(e.template))
+	} else {
+		hash.Write([]byte(e.Message))
+	}
+
+	if root := rootCause(e); root != error(e) {
+		hash.Write([]byte{0})
+		hash.Write([]byte(fmt.Sprintf("%T", root)))
+		hash.Write([]byte{0})
+		hash.Write([]byte(rootCode(root)))
+	}
+
+	frameCount := 0
+	for _, frame := range e.StackTrace.Frames() {
+		if frameCount == fingerprintFrameCount {
+			break
+		}
+
+		if isStandardLibraryFrame(frame.Function, frame.File) {
+			continue
+		}
+
+		hash.Write([]byte{0})
+		hash.Write([]byte(frame.Function))
+		frameCount++
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+// rootCause is the innermost error of the chain
+func rootCause(err error) error {
+	for {
+		var inner error
+		if appErr, isAppErr := AsApplicationError(err); isAppErr {
+			inner = appErr.Inner
+		} else {
+			inner = errors.Unwrap(err)
+		}
+
+		if inner == nil {
+			return err
+		}
+		err = inner
+	}
+}
+
+func rootCode(err error) string {
+	if appErr, isAppErr := AsApplicationError(err); isAppErr {
+		return appErr.Code
+	}
+
+	var sqlStateErr interface{ SQLState() string }
+	if errors.As(err, &sqlStateErr) {
+		return sqlStateErr.SQLState()
+	}
+
+	return ""
+}
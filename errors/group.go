@@ -0,0 +1,179 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Group runs functions in goroutines and collects their errors, like errgroup but keeping them as Errors. The first
+// internal error cancels the group's context, and panics are recovered into errors instead of crashing the process.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	mutex  sync.Mutex
+	errs   []Error
+}
+
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	groupCtx, cancel := context.WithCancelCause(ctx)
+
+	return &Group{
+		ctx:    groupCtx,
+		cancel: cancel,
+	}, groupCtx
+}
+
+func (g *Group) Go(f func(ctx context.Context) Error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				g.record(Recovered(recovered))
+			}
+		}()
+
+		if err := f(g.ctx); err != nil {
+			g.record(err)
+		}
+	}()
+}
+
+// Wait waits for every function to return and returns the first error, if any
+func (g *Group) Wait() Error {
+	g.wait()
+
+	if len(g.errs) == 0 {
+		return nil
+	}
+
+	return g.errs[0]
+}
+
+// WaitAll waits for every function to return and returns a *MultiError holding every error, or nil
+func (g *Group) WaitAll() Error {
+	g.wait()
+
+	if len(g.errs) == 0 {
+		return nil
+	}
+
+	return &MultiError{g.errs}
+}
+
+func (g *Group) wait() {
+	g.wg.Wait()
+	g.cancel(nil)
+}
+
+func (g *Group) record(err Error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.errs = append(g.errs, err)
+
+	if err.Internal() {
+		g.cancel(err)
+	}
+}
+
+// Recovered turns a value recovered from a panic into an error. It has to be called from the deferred function that
+// recovered, so the captured stack is the one that panicked.
+func Recovered(recovered interface{}) *ApplicationError {
+	if err, isErr := recovered.(error); isErr {
+		return newError(SeverityCritical, OriginApplication, "Recovered from panic: "+err.Error(), err)
+	}
+
+	return newError(SeverityCritical, OriginApplication, fmt.Sprintf("Recovered from panic: %v", recovered), nil)
+}
+
+type MultiError struct {
+	Errors []Error
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors occurred: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+func (e *MultiError) Internal() bool {
+	for _, err := range e.Errors {
+		if err.Internal() {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MultiError) Warning() bool {
+	for _, err := range e.Errors {
+		if !err.Warning() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *MultiError) Temporary() bool {
+	for _, err := range e.Errors {
+		if !err.Temporary() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *MultiError) Retryable() bool {
+	for _, err := range e.Errors {
+		if !err.Retryable() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (e *MultiError) MarshalJSON() ([]byte, error) {
+	encoded := make([]*errorJSON, len(e.Errors))
+	for i, err := range e.Errors {
+		encoded[i] = encodeError(err)
+	}
+
+	return json.Marshal(struct {
+		Message string       `json:"message"`
+		Errors  []*errorJSON `json:"errors"`
+	}{
+		e.Error(),
+		encoded,
+	})
+}
@@ -0,0 +1,226 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// errorJSON is the wire format for the whole error chain. Inner errors that aren't ApplicationErrors are kept as
+// message-only entries so the chain can be reconstructed on the other side.
+type errorJSON struct {
+	Message   string                 `json:"message"`
+	Code      string                 `json:"code,omitempty"`
+	Severity  string                 `json:"severity,omitempty"`
+	Origin    string                 `json:"origin,omitempty"`
+	Temporary bool                   `json:"temporary,omitempty"`
+	Retryable bool                   `json:"retryable,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Public    []string               `json:"publicDetails,omitempty"`
+	Template  string                 `json:"template,omitempty"`
+	Params    Params                 `json:"params,omitempty"`
+	Stack     []StackFrame           `json:"stack,omitempty"`
+	Query     string                 `json:"query,omitempty"`
+	QueryArgs []interface{}          `json:"queryArgs,omitempty"`
+	Inner     *errorJSON             `json:"inner,omitempty"`
+}
+
+// remoteError stands in for an inner error that was not an ApplicationError when it was serialized
+type remoteError struct {
+	message string
+	inner   error
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.inner
+}
+
+func (e *ApplicationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeError(e))
+}
+
+func (e *ApplicationError) UnmarshalJSON(data []byte) error {
+	var encoded errorJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	*e = decodeApplicationError(&encoded)
+	return nil
+}
+
+func (e *QueryError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeError(e))
+}
+
+func (e *QueryError) UnmarshalJSON(data []byte) error {
+	var encoded errorJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	*e = QueryError{
+		decodeApplicationError(&encoded),
+		encoded.Query,
+		encoded.QueryArgs,
+	}
+	return nil
+}
+
+// FromJSON reconstructs an error serialized by MarshalJSON, returning a *QueryError if the top of the chain was one
+func FromJSON(data []byte) (Error, error) {
+	var encoded errorJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	if appErr, isAppErr := decodeError(&encoded).(Error); isAppErr {
+		return appErr, nil
+	}
+
+	return nil, errors.New("serialized error has no severity or origin")
+}
+
+func encodeError(err error) *errorJSON {
+	if err == nil {
+		return nil
+	}
+
+	switch e := err.(type) {
+	case *QueryError:
+		encoded := encodeApplicationError(&e.ApplicationError)
+		encoded.Query = e.Query
+		encoded.QueryArgs = e.RedactedArgs()
+		return encoded
+	case *ApplicationError:
+		return encodeApplicationError(e)
+	}
+
+	return &errorJSON{
+		Message: err.Error(),
+		Inner:   encodeError(errors.Unwrap(err)),
+	}
+}
+
+func encodeApplicationError(e *ApplicationError) *errorJSON {
+	var details map[string]interface{}
+	var public []string
+	for key, d := range e.details {
+		if details == nil {
+			details = make(map[string]interface{}, len(e.details))
+		}
+		details[key] = d.value
+
+		if d.public {
+			public = append(public, key)
+		}
+	}
+	sort.Strings(public)
+
+	return &errorJSON{
+		Message:   e.Message,
+		Code:      e.Code,
+		Severity:  SeverityString(e.Severity),
+		Origin:    OriginString(e.Origin),
+		Temporary: e.temporary,
+		Retryable: e.retryable,
+		Details:   details,
+		Public:    public,
+		Template:  e.template,
+		Params:    e.params,
+		Stack:     e.StackTrace.Frames(),
+		Inner:     encodeError(e.Inner),
+	}
+}
+
+func decodeError(encoded *errorJSON) error {
+	if encoded == nil {
+		return nil
+	}
+
+	if encoded.Severity == "" && encoded.Origin == "" {
+		return &remoteError{
+			encoded.Message,
+			decodeError(encoded.Inner),
+		}
+	}
+
+	appErr := decodeApplicationError(encoded)
+
+	if encoded.Query != "" {
+		return &QueryError{
+			appErr,
+			encoded.Query,
+			encoded.QueryArgs,
+		}
+	}
+
+	return &appErr
+}
+
+func decodeApplicationError(encoded *errorJSON) ApplicationError {
+	var stack *Stack
+	if len(encoded.Stack) > 0 {
+		stack = &Stack{frames: encoded.Stack}
+	}
+
+	var details map[string]detail
+	for key, value := range encoded.Details {
+		if details == nil {
+			details = make(map[string]detail, len(encoded.Details))
+		}
+		details[key] = detail{value, false}
+	}
+	for _, key := range encoded.Public {
+		if d, exists := details[key]; exists {
+			d.public = true
+			details[key] = d
+		}
+	}
+
+	return ApplicationError{
+		Severity:   parseSeverity(encoded.Severity),
+		Origin:     parseOrigin(encoded.Origin),
+		Code:       encoded.Code,
+		Message:    encoded.Message,
+		Inner:      decodeError(encoded.Inner),
+		StackTrace: stack,
+		temporary:  encoded.Temporary,
+		retryable:  encoded.Retryable,
+		details:    details,
+		template:   encoded.Template,
+		params:     encoded.Params,
+	}
+}
+
+func parseSeverity(severity string) Severity {
+	switch severity {
+	case "warning":
+		return SeverityWarning
+	case "info":
+		return SeverityInfo
+	case "debug":
+		return SeverityDebug
+	case "critical":
+		return SeverityCritical
+	}
+
+	return SeverityError
+}
+
+func parseOrigin(origin string) Origin {
+	switch origin {
+	case "input":
+		return OriginInput
+	case "third-party":
+		return OriginThirdParty
+	case "client":
+		return OriginClient
+	}
+
+	return OriginApplication
+}
@@ -0,0 +1,175 @@
+package errors
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// SensitiveValue wraps a query argument so it is passed to the database as usual but never logged
+type SensitiveValue struct {
+	value interface{}
+}
+
+func Sensitive(value interface{}) SensitiveValue {
+	return SensitiveValue{value}
+}
+
+func (s SensitiveValue) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.value)
+}
+
+func (s SensitiveValue) String() string {
+	return Redacted
+}
+
+func (s SensitiveValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+type RedactionPolicy struct {
+	Positions []int          // zero-based positions of positional query args
+	Names     []string       // named parameters, matched against map keys and db struct tags
+	Types     []reflect.Type // values of these types are redacted wherever they appear in the args
+}
+
+var redactionPolicy RedactionPolicy
+
+// SetRedactionPolicy chooses which query args are redacted, in addition to SensitiveValues
+func SetRedactionPolicy(policy RedactionPolicy) {
+	redactionPolicy = policy
+}
+
+// RedactedArgs is what gets logged and serialized in place of Args. Named parameter structs are flattened into maps
+// keyed by their db names, the same way sqlx sees them.
+func (e *QueryError) RedactedArgs() []interface{} {
+	if e.Args == nil {
+		return nil
+	}
+
+	redacted := make([]interface{}, len(e.Args))
+	for i, arg := range e.Args {
+		if slices.Contains(redactionPolicy.Positions, i) {
+			redacted[i] = Redacted
+		} else {
+			redacted[i] = redactValue(reflect.ValueOf(arg), true)
+		}
+	}
+
+	return redacted
+}
+
+// only top level structs are treated as named parameters, nested ones are left alone unless their type is redacted
+func redactValue(value reflect.Value, topLevel bool) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+
+	if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+
+		if value.Kind() == reflect.Pointer && isRedactedType(value.Type()) {
+			return Redacted
+		}
+
+		return redactValue(value.Elem(), topLevel)
+	}
+
+	if isRedactedType(value.Type()) {
+		return Redacted
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		redacted := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			if slices.Contains(redactionPolicy.Names, name) {
+				redacted[name] = Redacted
+			} else {
+				redacted[name] = redactValue(iter.Value(), false)
+			}
+		}
+		return redacted
+	case reflect.Struct:
+		if !topLevel {
+			break
+		}
+
+		if _, isValuer := value.Interface().(driver.Valuer); isValuer {
+			break
+		}
+
+		if _, isMarshaler := value.Interface().(json.Marshaler); isMarshaler {
+			break
+		}
+
+		redacted := make(map[string]interface{}, value.NumField())
+		redactStructFields(value, redacted)
+		return redacted
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		redacted := make([]interface{}, value.Len())
+		for i := range redacted {
+			redacted[i] = redactValue(value.Index(i), topLevel)
+		}
+		return redacted
+	}
+
+	return value.Interface()
+}
+
+func redactStructFields(value reflect.Value, redacted map[string]interface{}) {
+	structType := value.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, tagged := field.Tag.Lookup("db")
+		if name == "-" {
+			continue
+		}
+
+		name, _, _ = strings.Cut(name, ",")
+
+		if field.Anonymous && !tagged && field.Type.Kind() == reflect.Struct {
+			redactStructFields(value.Field(i), redacted)
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name) // sqlx's default name mapping
+		}
+
+		if slices.Contains(redactionPolicy.Names, name) {
+			redacted[name] = Redacted
+		} else {
+			redacted[name] = redactValue(value.Field(i), false)
+		}
+	}
+}
+
+func isRedactedType(valueType reflect.Type) bool {
+	if valueType == reflect.TypeOf(SensitiveValue{}) {
+		return true
+	}
+
+	return slices.Contains(redactionPolicy.Types, valueType)
+}
@@ -0,0 +1,227 @@
+package errors
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+type StackTraceOptions struct {
+	MaxDepth      int  // maximum number of frames captured, 0 disables capture entirely
+	FilterRuntime bool // drop runtime and standard library frames when resolving
+	SkipInput     bool // don't capture stacks for input-origin errors
+	Enrich        bool // fill in package, short file and in-app flag on resolved frames
+
+	// module path of the application, for deciding which frames are in-app. Defaults to the main module of the binary.
+	AppModule string
+}
+
+var stackTraceOptions = StackTraceOptions{
+	MaxDepth:      32,
+	FilterRuntime: true,
+	SkipInput:     false,
+	Enrich:        false,
+}
+
+// SetStackTraceOptions applies to errors created after it's called
+func SetStackTraceOptions(options StackTraceOptions) {
+	stackTraceOptions = options
+}
+
+// Stack holds the raw program counters of a captured stack. Frames are only resolved when first asked for, which is
+// usually when the error gets logged.
+type Stack struct {
+	pcs     []uintptr
+	frames  []StackFrame
+	resolve sync.Once
+}
+
+func (s *Stack) Frames() []StackFrame {
+	if s == nil {
+		return nil
+	}
+
+	s.resolve.Do(func() {
+		if s.frames == nil && len(s.pcs) > 0 {
+			s.frames = resolveFrames(s.pcs, stackTraceOptions)
+		}
+	})
+
+	return s.frames
+}
+
+// FirstAppFrame is the innermost frame that belongs to the application module, which is usually where to start looking.
+// It's only found when StackTraceOptions.Enrich is set.
+func (s *Stack) FirstAppFrame() (StackFrame, bool) {
+	if !stackTraceOptions.Enrich {
+		return StackFrame{}, false
+	}
+
+	for _, frame := range s.Frames() {
+		if frame.InApp {
+			return frame, true
+		}
+	}
+
+	return StackFrame{}, false
+}
+
+func (s *Stack) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Frames())
+}
+
+func captureStack(origin Origin, skip int) *Stack {
+	options := stackTraceOptions
+	if options.MaxDepth <= 0 || (options.SkipInput && origin == OriginInput) {
+		return nil
+	}
+
+	pcs := make([]uintptr, options.MaxDepth)
+
+	n := runtime.Callers(4+skip, pcs) // skip Callers itself, captureStack, newErrorSkip, the skipped frames and the constructor
+
+	if n == 0 {
+		return nil
+	}
+
+	return &Stack{pcs: pcs[:n:n]}
+}
+
+func resolveFrames(pcs []uintptr, options StackTraceOptions) []StackFrame {
+	callersFrames := runtime.CallersFrames(pcs)
+
+	if callersFrames == nil {
+		return nil
+	}
+
+	frames := make([]StackFrame, 0, len(pcs))
+
+	for {
+		frame, more := callersFrames.Next()
+
+		if !options.FilterRuntime || !isStandardLibraryFrame(frame.Function, frame.File) {
+			stackFrame := StackFrame{
+				File:     frame.File,
+				FileLine: frame.Line,
+				Function: frame.Function,
+			}
+
+			if options.Enrich {
+				enrichFrame(&stackFrame, options.AppModule)
+			}
+
+			frames = append(frames, stackFrame)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// standard library frames are the ones compiled from the standard library's source directory. Binaries built with
+// -trimpath don't have that directory in their file paths, so there it falls back to the package path: anything not in
+// a module of the build whose import path doesn't start with a domain, e.g. runtime or net/http.
+func isStandardLibraryFrame(function string, file string) bool {
+	if function == "" {
+		return true
+	}
+
+	if root := standardLibraryRoot(); root != "" {
+		return strings.HasPrefix(file, root)
+	}
+
+	return isStandardLibraryPackage(functionPackage(function), buildModules())
+}
+
+func isStandardLibraryPackage(pkg string, modules []string) bool {
+	if pkg == "main" {
+		return false
+	}
+
+	for _, module := range modules {
+		if module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/")) {
+			return false
+		}
+	}
+
+	firstElement, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(firstElement, ".")
+}
+
+// standardLibraryRoot is the directory the standard library was compiled from, found from the file of a function known
+// to be in it. Empty when built with -trimpath.
+var standardLibraryRoot = sync.OnceValue(func() string {
+	pc := reflect.ValueOf(strings.Cut).Pointer()
+	file, _ := runtime.FuncForPC(pc).FileLine(pc)
+
+	root, found := strings.CutSuffix(file, "strings/strings.go")
+	if !found || root == "" {
+		return ""
+	}
+
+	return root
+})
+
+// buildModules are the paths of the main module and its dependencies
+var buildModules = sync.OnceValue(func() []string {
+	buildInfo, exists := debug.ReadBuildInfo()
+	if !exists {
+		return nil
+	}
+
+	modules := []string{buildInfo.Main.Path}
+	for _, dependency := range buildInfo.Deps {
+		modules = append(modules, dependency.Path)
+	}
+
+	return modules
+})
+
+var mainModule = func() string {
+	if modules := buildModules(); len(modules) > 0 {
+		return modules[0]
+	}
+
+	return ""
+}
+
+func enrichFrame(frame *StackFrame, appModule string) {
+	if appModule == "" {
+		appModule = mainModule()
+	}
+
+	frame.Package = functionPackage(frame.Function)
+	frame.InApp = frame.Package == "main" || (appModule != "" && (frame.Package == appModule || strings.HasPrefix(frame.Package, appModule+"/")))
+
+	fileName := path.Base(frame.File)
+	switch {
+	case frame.Package == "main" || frame.Package == "":
+		frame.ShortFile = fileName
+	case frame.InApp && frame.Package == appModule:
+		frame.ShortFile = fileName
+	case frame.InApp:
+		frame.ShortFile = strings.TrimPrefix(frame.Package, appModule+"/") + "/" + fileName
+	default:
+		frame.ShortFile = frame.Package + "/" + fileName
+	}
+}
+
+// function names look like github.com/owner/repo/pkg.(*Type).Method, and the package is everything up to the first dot
+// after the last slash
+func functionPackage(function string) string {
+	lastSlash := strings.LastIndex(function, "/")
+
+	dot := strings.Index(function[lastSlash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+
+	return function[:lastSlash+1+dot]
+}
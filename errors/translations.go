@@ -0,0 +1,142 @@
+package errors
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when none of the requested locales have a translation
+var DefaultLocale = "en"
+
+// Translations holds public message templates by locale and then error code
+type Translations struct {
+	mutex    sync.RWMutex
+	messages map[string]map[string]string
+}
+
+func NewTranslations() *Translations {
+	return &Translations{
+		messages: make(map[string]map[string]string),
+	}
+}
+
+var DefaultTranslations = NewTranslations()
+
+func (t *Translations) Add(locale string, messages map[string]string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	locale = normalizeLocale(locale)
+	if t.messages[locale] == nil {
+		t.messages[locale] = make(map[string]string, len(messages))
+	}
+
+	for code, message := range messages {
+		t.messages[locale][code] = message
+	}
+}
+
+// LoadFS loads every file matching pattern as a JSON object of code to message template. The file name without its
+// extension is the locale, e.g. en-US.json.
+func (t *Translations) LoadFS(fsys fs.FS, pattern string) error {
+	fileNames, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, fileName := range fileNames {
+		data, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return err
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return Wrap(err, "Failed to parse translations file "+fileName)
+		}
+
+		base := path.Base(fileName)
+		t.Add(strings.TrimSuffix(base, path.Ext(base)), messages)
+	}
+
+	return nil
+}
+
+// Lookup tries each locale in order, then each one's base language, then DefaultLocale
+func (t *Translations) Lookup(code string, locales ...string) (string, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, locale := range locales {
+		locale = normalizeLocale(locale)
+		if message, exists := t.messages[locale][code]; exists {
+			return message, true
+		}
+
+		if language, _, isRegional := strings.Cut(locale, "-"); isRegional {
+			if message, exists := t.messages[language][code]; exists {
+				return message, true
+			}
+		}
+	}
+
+	message, exists := t.messages[normalizeLocale(DefaultLocale)][code]
+	return message, exists
+}
+
+// LoadTranslations loads *.json translation files from dir into DefaultTranslations
+func LoadTranslations(dir string) error {
+	return DefaultTranslations.LoadFS(os.DirFS(dir), "*.json")
+}
+
+// LocalizedMessage is the public message for the error in the first of the locales that has a translation. Params come
+// from the catalog error in the chain. Without a translation it's the catalog error's message, and errors that didn't
+// come from the catalog fall back to their own message.
+func (e *ApplicationError) LocalizedMessage(locales ...string) string {
+	if e.Code == "" {
+		return e.Message
+	}
+
+	catalogErr := e.catalogError()
+
+	template, exists := DefaultTranslations.Lookup(e.Code, locales...)
+	if !exists {
+		if catalogErr != nil {
+			return catalogErr.Message
+		}
+
+		return e.Message
+	}
+
+	if catalogErr != nil {
+		return interpolate(template, catalogErr.params)
+	}
+
+	return template
+}
+
+// catalogError is the error in the chain created from the catalog, which has the public message and its params
+func (e *ApplicationError) catalogError() *ApplicationError {
+	for err := error(e); err != nil; {
+		appErr, isAppErr := AsApplicationError(err)
+		if !isAppErr {
+			break
+		}
+
+		if appErr.template != "" {
+			return appErr
+		}
+
+		err = appErr.Inner
+	}
+
+	return nil
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
@@ -1,13 +1,15 @@
 module github.com/sjohna/go-server-common
 
-go 1.21
+go 1.23
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.4
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.33.0
 	github.com/sirupsen/logrus v1.9.0
 	github.com/stretchr/testify v1.7.0
+	github.com/ugorji/go/codec v1.3.2
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
 
@@ -16,6 +18,7 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/sys v0.12.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"encoding"
+	"github.com/sjohna/go-server-common/errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CodeInvalidParams = "invalid_params"
+
+func init() {
+	errors.Register(errors.Definition{
+		Code:        CodeInvalidParams,
+		HTTPStatus:  http.StatusBadRequest,
+		Severity:    errors.SeverityInfo,
+		Origin:      errors.OriginInput,
+		Message:     "Invalid request parameters",
+		Description: "One or more query, path, header or cookie parameters are missing or invalid. The fields detail lists each of them.",
+	})
+}
+
+// FieldError describes one bad parameter, and is returned to the client in the fields detail
+type FieldError struct {
+	In      string `json:"in"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// BindQuery fills the fields of the struct dst points to from query parameters, by their query tag, e.g.
+//
+//	type listParams struct {
+//		Limit  int       `query:"limit" default:"20"`
+//		Order  string    `query:"order" enum:"asc,desc"`
+//		Since  time.Time `query:"since"`
+//		Status []string  `query:"status"`
+//		Page   int       `query:"page,default=1"`
+//		Token  string    `header:"X-Token,required"`
+//	}
+//
+// Options follow the name, separated by commas: required, and default=value as a shorter form of the default tag for
+// values without commas.
+// Supported field types are strings, bools, ints, uints, floats, time.Time (RFC 3339), time.Duration, anything
+// implementing encoding.TextUnmarshaler, pointers to those, and slices of those, which take repeated parameters.
+// Missing parameters leave the field alone unless it has a default. Every bad parameter is reported in one input error.
+func BindQuery(r *http.Request, dst interface{}) errors.Error {
+	query := r.URL.Query()
+	return bind(dst, "query", func(name string) []string {
+		return query[name]
+	})
+}
+
+// BindPath binds path parameters of the matched route by their path tag
+func BindPath(r *http.Request, dst interface{}) errors.Error {
+	return bind(dst, "path", func(name string) []string {
+		if value := r.PathValue(name); value != "" {
+			return []string{value}
+		}
+
+		return nil
+	})
+}
+
+func BindHeader(r *http.Request, dst interface{}) errors.Error {
+	return bind(dst, "header", func(name string) []string {
+		return r.Header.Values(name)
+	})
+}
+
+func BindCookie(r *http.Request, dst interface{}) errors.Error {
+	return bind(dst, "cookie", func(name string) []string {
+		var values []string
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == name {
+				values = append(values, cookie.Value)
+			}
+		}
+
+		return values
+	})
+}
+
+// Bind binds query, path, header and cookie parameters in one go, reporting the bad ones together
+func Bind(r *http.Request, dst interface{}) errors.Error {
+	var fields []FieldError
+	for _, binder := range []func(*http.Request, interface{}) errors.Error{BindPath, BindQuery, BindHeader, BindCookie} {
+		err := binder(r, dst)
+		if err == nil {
+			continue
+		}
+
+		appErr, _ := errors.AsApplicationError(err)
+		if appErr == nil || appErr.Code != CodeInvalidParams {
+			return err
+		}
+
+		fields = append(fields, appErr.PublicDetails()["fields"].([]FieldError)...)
+	}
+
+	return invalidParams(fields)
+}
+
+func bind(dst interface{}, source string, lookup func(name string) []string) errors.Error {
+	target := reflect.ValueOf(dst)
+	if !target.IsValid() {
+		return errors.New("Can only bind " + source + " parameters into a pointer to a struct, not nil")
+	}
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return errors.New("Can only bind " + source + " parameters into a pointer to a struct, not " + target.Type().String())
+	}
+
+	var fields []FieldError
+	err := bindStruct(target.Elem(), source, lookup, &fields)
+	if err != nil {
+		return err
+	}
+
+	return invalidParams(fields)
+}
+
+func invalidParams(fields []FieldError) errors.Error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return errors.FromCode(CodeInvalidParams, nil).WithPublic("fields", fields)
+}
+
+func bindStruct(target reflect.Value, source string, lookup func(name string) []string, fields *[]FieldError) errors.Error {
+	targetType := target.Type()
+
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		tag, tagged := field.Tag.Lookup(source)
+
+		// the fields of embedded structs are bound as if they were in the outer struct, even if the type is unexported
+		if !tagged && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindStruct(target.Field(i), source, lookup, fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !tagged || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		required := false
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+		for _, option := range strings.Split(options, ",") {
+			switch {
+			case option == "":
+			case option == "required":
+				required = true
+			case strings.HasPrefix(option, "default="):
+				defaultValue, hasDefault = strings.TrimPrefix(option, "default="), true
+			default:
+				return errors.New("Unknown option " + option + " in " + source + " tag of field " + field.Name)
+			}
+		}
+
+		values := lookup(name)
+		if len(values) == 0 {
+			if hasDefault {
+				values = []string{defaultValue}
+			} else if required {
+				*fields = append(*fields, FieldError{source, name, "is required"})
+				continue
+			} else {
+				continue
+			}
+		}
+
+		var enum []string
+		if enumTag, hasEnum := field.Tag.Lookup("enum"); hasEnum {
+			enum = strings.Split(enumTag, ",")
+		}
+
+		message, err := setField(target.Field(i), values, enum)
+		if err != nil {
+			return errors.Wrap(err, "Can't bind "+source+" parameter "+name+" into field "+field.Name)
+		}
+
+		if message != "" {
+			*fields = append(*fields, FieldError{source, name, message})
+		}
+	}
+
+	return nil
+}
+
+// setField returns a message for the client if a value is bad, and an error if the field's type isn't supported
+func setField(field reflect.Value, values []string, enum []string) (string, errors.Error) {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 && !isTextUnmarshaler(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			message, err := setValue(slice.Index(i), value, enum)
+			if message != "" || err != nil {
+				return message, err
+			}
+		}
+
+		field.Set(slice)
+		return "", nil
+	}
+
+	return setValue(field, values[0], enum)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func isTextUnmarshaler(field reflect.Value) bool {
+	_, isUnmarshaler := field.Addr().Interface().(encoding.TextUnmarshaler)
+	return isUnmarshaler
+}
+
+func setValue(field reflect.Value, value string, enum []string) (string, errors.Error) {
+	if enum != nil && !slices.Contains(enum, value) {
+		return "must be one of " + strings.Join(enum, ", "), nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		pointer := reflect.New(field.Type().Elem())
+		message, err := setValue(pointer.Elem(), value, nil)
+		if message == "" && err == nil {
+			field.Set(pointer)
+		}
+
+		return message, err
+	}
+
+	switch field.Type() {
+	case timeType:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "must be an RFC 3339 time", nil
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return "", nil
+	case durationType:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return "must be a duration like 1m30s", nil
+		}
+		field.SetInt(int64(parsed))
+		return "", nil
+	}
+
+	if unmarshaler, isUnmarshaler := field.Addr().Interface().(encoding.TextUnmarshaler); isUnmarshaler {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return "is invalid: " + err.Error(), nil
+		}
+		return "", nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return "must be true or false", nil
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return "must be an integer", nil
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return "must be a non-negative integer", nil
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return "must be a number", nil
+		}
+		field.SetFloat(parsed)
+	default:
+		return "", errors.New("Unsupported field type " + field.Type().String())
+	}
+
+	return "", nil
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type pagination struct {
+	Limit  int `query:"limit" default:"20"`
+	Offset int `query:"offset"`
+}
+
+type listParams struct {
+	pagination
+	Order   string        `query:"order" enum:"asc,desc" default:"asc"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Status  []string      `query:"status"`
+	Active  *bool         `query:"active"`
+	Weight  float64       `query:"weight"`
+	IP      net.IP        `query:"ip"`
+	Token   string        `header:"X-Token,required"`
+	Session string        `cookie:"session"`
+	ID      int64         `path:"id"`
+}
+
+func TestBindQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?offset=40&since=2024-03-01T12:00:00Z&timeout=1m30s&status=open&status=closed&active=true&weight=1.5&ip=10.0.0.1", nil)
+
+	var params listParams
+	err := BindQuery(r, &params)
+	require.Nil(t, err)
+
+	assert.Equal(t, 20, params.Limit)
+	assert.Equal(t, 40, params.Offset)
+	assert.Equal(t, "asc", params.Order)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), params.Since)
+	assert.Equal(t, 90*time.Second, params.Timeout)
+	assert.Equal(t, []string{"open", "closed"}, params.Status)
+	require.NotNil(t, params.Active)
+	assert.True(t, *params.Active)
+	assert.Equal(t, 1.5, params.Weight)
+	assert.Equal(t, "10.0.0.1", params.IP.String())
+	assert.Empty(t, params.Token, "header fields aren't bound from the query")
+}
+
+func TestBindQueryErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?limit=ten&order=sideways&since=yesterday&active=maybe", nil)
+
+	var params listParams
+	err := BindQuery(r, &params)
+	require.NotNil(t, err)
+	assert.False(t, err.Internal())
+	assert.Equal(t, http.StatusBadRequest, StatusCode(err))
+
+	appErr, _ := errors.AsApplicationError(err)
+	assert.Equal(t, []FieldError{
+		{"query", "limit", "must be an integer"},
+		{"query", "order", "must be one of asc, desc"},
+		{"query", "since", "must be an RFC 3339 time"},
+		{"query", "active", "must be true or false"},
+	}, appErr.PublicDetails()["fields"])
+}
+
+func TestBind(t *testing.T) {
+	router := NewRouter()
+
+	var params listParams
+	var bindErr errors.Error
+	router.Get("/items/{id}", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		params = listParams{}
+		bindErr = Bind(r, &params)
+		return nil, bindErr
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/items/7?limit=5", nil)
+	r.Header.Set("X-Token", "abc")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Nil(t, bindErr)
+	assert.Equal(t, int64(7), params.ID)
+	assert.Equal(t, 5, params.Limit)
+	assert.Equal(t, "abc", params.Token)
+	assert.Equal(t, "s1", params.Session)
+
+	recorder := serve(router, http.MethodGet, "/items/x")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, CodeInvalidParams, decodeProblem(t, recorder).Code)
+
+	appErr, _ := errors.AsApplicationError(bindErr)
+	assert.Equal(t, []FieldError{
+		{"path", "id", "must be an integer"},
+		{"header", "X-Token", "is required"},
+	}, appErr.PublicDetails()["fields"])
+}
+
+func TestBindUnsupported(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?value=1", nil)
+
+	var notStruct int
+	assert.True(t, BindQuery(r, &notStruct).Internal())
+	assert.True(t, BindQuery(r, nil).Internal())
+
+	var nilStruct *struct {
+		Value int `query:"value"`
+	}
+	assert.True(t, BindQuery(r, nilStruct).Internal())
+
+	var unsupported struct {
+		Value map[string]string `query:"value"`
+	}
+	assert.True(t, BindQuery(r, &unsupported).Internal())
+}
+
+func TestBindOptions(t *testing.T) {
+	var params struct {
+		Page  int    `query:"page,required,default=1"`
+		Query string `query:"q,required"`
+		Sort  string `query:",default=name"`
+	}
+
+	err := BindQuery(httptest.NewRequest(http.MethodGet, "/?q=x", nil), &params)
+	require.Nil(t, err)
+	assert.Equal(t, 1, params.Page)
+	assert.Equal(t, "x", params.Query)
+	assert.Equal(t, "name", params.Sort)
+
+	err = BindQuery(httptest.NewRequest(http.MethodGet, "/", nil), &params)
+	appErr, _ := errors.AsApplicationError(err)
+	require.NotNil(t, appErr)
+	assert.Equal(t, []FieldError{{"query", "q", "is required"}}, appErr.PublicDetails()["fields"])
+
+	var unknown struct {
+		Page int `query:"page,optional"`
+	}
+	assert.True(t, BindQuery(httptest.NewRequest(http.MethodGet, "/", nil), &unknown).Internal())
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"io"
+	"reflect"
+)
+
+// CBOR follows the json tags of the value like MessagePack does, with binary []byte fields. Times are RFC 3339 text
+// like in JSON, and UseNumber doesn't apply.
+
+var cborEncMode = mustCBOR(cbor.EncOptions{
+	Sort: cbor.SortCoreDeterministic,
+	Time: cbor.TimeRFC3339Nano,
+}.EncMode())
+
+var cborDecOptions = cbor.DecOptions{
+	DefaultMapType:  reflect.TypeOf(map[string]interface{}(nil)),
+	MaxNestedLevels: maxDecodeDepth,
+}
+
+var cborDecMode = mustCBOR(cborDecOptions.DecMode())
+
+var cborStrictDecMode = mustCBOR(cbor.DecOptions{
+	DefaultMapType:    cborDecOptions.DefaultMapType,
+	MaxNestedLevels:   cborDecOptions.MaxNestedLevels,
+	ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+}.DecMode())
+
+func mustCBOR[T any](mode T, err error) T {
+	if err != nil {
+		panic("Invalid CBOR options: " + err.Error())
+	}
+
+	return mode
+}
+
+func encodeCBOR(w io.Writer, value interface{}, _ map[string]string) error {
+	return cborEncMode.NewEncoder(w).Encode(value)
+}
+
+func decodeCBOR(r io.Reader, value interface{}, options DecodeOptions) error {
+	data, err := readBinary(r)
+	if err != nil {
+		return err
+	}
+
+	mode := cborDecMode
+	if options.DisallowUnknownFields {
+		mode = cborStrictDecMode
+	}
+
+	rest, err := mode.UnmarshalFirst(data, value)
+	if err != nil {
+		return binaryError(err)
+	}
+
+	if options.DisallowTrailingData && len(rest) > 0 {
+		return errTrailingData
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+)
+
+// Encoder writes value in one media type. params are the parameters of the Accept entry that selected it, e.g. pretty
+// in "application/json; pretty".
+type Encoder func(w io.Writer, value interface{}, params map[string]string) error
+
+// Decoder reads a request body in one media type into value
+type Decoder func(r io.Reader, value interface{}, options DecodeOptions) error
+
+type encoderEntry struct {
+	mediaType   string
+	contentType string
+	encode      Encoder
+}
+
+var encoders []encoderEntry
+var decoders = make(map[string]Decoder)
+
+// RegisterEncoder makes responses available as mediaType. Clients have to name it in their Accept header to get it,
+// since wildcards like */* pick JSON whenever they match it.
+func RegisterEncoder(mediaType string, contentType string, encoder Encoder) {
+	for i, entry := range encoders {
+		if entry.mediaType == mediaType {
+			encoders[i] = encoderEntry{mediaType, contentType, encoder}
+			return
+		}
+	}
+
+	encoders = append(encoders, encoderEntry{mediaType, contentType, encoder})
+}
+
+// RegisterDecoder accepts request bodies with a Content-Type of mediaType
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	decoders[mediaType] = decoder
+}
+
+func init() {
+	RegisterEncoder("application/json", "application/json", encodeJSON)
+	RegisterEncoder("application/msgpack", "application/msgpack", encodeMsgpack)
+	RegisterEncoder("application/x-msgpack", "application/x-msgpack", encodeMsgpack)
+	RegisterEncoder("application/cbor", "application/cbor", encodeCBOR)
+	RegisterEncoder("text/csv", "text/csv; charset=utf-8", encodeCSV)
+
+	RegisterDecoder("application/json", decodeJSON)
+	RegisterDecoder("application/msgpack", decodeMsgpack)
+	RegisterDecoder("application/x-msgpack", decodeMsgpack)
+	RegisterDecoder("application/cbor", decodeCBOR)
+}
+
+func encodeJSON(w io.Writer, value interface{}, params map[string]string) error {
+	var body []byte
+	var err error
+	if _, pretty := params["pretty"]; pretty {
+		body, err = json.MarshalIndent(value, "", "  ")
+	} else {
+		body, err = json.Marshal(value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+	return err
+}
+
+// newJSONDecoder is the one place DecodeOptions turn into settings of a json.Decoder
+func newJSONDecoder(r io.Reader, options DecodeOptions) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if options.UseNumber {
+		decoder.UseNumber()
+	}
+
+	return decoder
+}
+
+func decodeJSON(r io.Reader, value interface{}, options DecodeOptions) error {
+	decoder := newJSONDecoder(r, options)
+	err := decoder.Decode(value)
+	if err != nil {
+		return err
+	}
+
+	if options.DisallowTrailingData {
+		_, err = decoder.Token()
+		if err != io.EOF {
+			if err != nil {
+				return err
+			}
+
+			return errTrailingData
+		}
+	}
+
+	return nil
+}
+
+// toGeneric turns value into what decoding its JSON into interface{} would give, so CSV columns follow the json
+// tags of the value instead of needing tags of their own
+func toGeneric(value interface{}) (interface{}, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+// nesting deeper than this in a binary request body is rejected rather than recursed into
+const maxDecodeDepth = 100
+
+var errMalformed = stderrors.New("malformed or truncated body")
+
+// readBinary buffers a binary body, which has to be read whole before it can be decoded
+func readBinary(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+
+	return data, nil
+}
+
+// binaryError keeps a binary body that ends early from being reported as empty or as bad JSON
+func binaryError(err error) error {
+	if stderrors.Is(err, io.EOF) || stderrors.Is(err, io.ErrUnexpectedEOF) {
+		return errMalformed
+	}
+
+	return err
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type codecItem struct {
+	ID    int64    `json:"id" xml:"id"`
+	Name  string   `json:"name" xml:"name"`
+	Tags  []string `json:"tags,omitempty" xml:"tags"`
+	Price float64  `json:"price" xml:"price"`
+	Data  []byte   `json:"data,omitempty" xml:"-"`
+}
+
+func encodeHex(t *testing.T, encoder Encoder, value interface{}) string {
+	var buf bytes.Buffer
+	require.NoError(t, encoder(&buf, value, nil))
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestBinaryEncoding(t *testing.T) {
+	value := map[string]interface{}{"a": 1, "b": []int{2, 3}}
+
+	// the example from RFC 8949 appendix A
+	assert.Equal(t, "a26161016162820203", encodeHex(t, encodeCBOR, value))
+	assert.Equal(t, "82a16101a162920203", encodeHex(t, encodeMsgpack, value))
+
+	assert.Equal(t, "3903e7", encodeHex(t, encodeCBOR, -1000))
+	assert.Equal(t, "d1fc18", encodeHex(t, encodeMsgpack, -1000))
+	assert.Equal(t, "fb3ff8000000000000", encodeHex(t, encodeCBOR, 1.5))
+	assert.Equal(t, "cfffffffffffffffff", encodeHex(t, encodeMsgpack, uint64(1<<64-1)))
+
+	// bytes are binary, not base64 text like in JSON
+	assert.Equal(t, "c403010203", encodeHex(t, encodeMsgpack, []byte{1, 2, 3}))
+	assert.Equal(t, "43010203", encodeHex(t, encodeCBOR, []byte{1, 2, 3}))
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	item := codecItem{
+		ID:    -70000,
+		Name:  strings.Repeat("long name ", 10),
+		Tags:  []string{"a", "b"},
+		Price: 12.25,
+		Data:  []byte{0, 1, 2},
+	}
+
+	for name, codec := range map[string]struct {
+		encode Encoder
+		decode Decoder
+	}{
+		"MessagePack": {encodeMsgpack, decodeMsgpack},
+		"CBOR":        {encodeCBOR, decodeCBOR},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, codec.encode(&buf, item, nil))
+
+			var decoded codecItem
+			require.NoError(t, codec.decode(bytes.NewReader(buf.Bytes()), &decoded, decodeOptions))
+			assert.Equal(t, item, decoded)
+
+			encoded := buf.Bytes()
+			assert.Error(t, codec.decode(bytes.NewReader(encoded[:len(encoded)-1]), &decoded, decodeOptions))
+			assert.Equal(t, errTrailingData, codec.decode(bytes.NewReader(append(encoded, 0)), &decoded, decodeOptions))
+
+			buf.Reset()
+			require.NoError(t, codec.encode(&buf, map[string]interface{}{"id": 1, "unknown": true}, nil))
+			assert.NoError(t, codec.decode(bytes.NewReader(buf.Bytes()), &decoded, decodeOptions))
+			assert.Error(t, codec.decode(bytes.NewReader(buf.Bytes()), &decoded, DecodeOptions{DisallowUnknownFields: true}))
+		})
+	}
+
+	// an array claiming to have billions of elements is rejected before anything is allocated for them
+	var decoded []int
+	assert.Error(t, decodeMsgpack(bytes.NewReader([]byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}), &decoded, decodeOptions))
+	assert.Error(t, decodeCBOR(bytes.NewReader([]byte{0x9a, 0xff, 0xff, 0xff, 0xff, 0x01}), &decoded, decodeOptions))
+
+	// so is nesting deeper than maxDecodeDepth
+	var nested interface{}
+	assert.Error(t, decodeMsgpack(bytes.NewReader(append(bytes.Repeat([]byte{0x91}, maxDecodeDepth+1), 0xc0)), &nested, decodeOptions))
+	assert.Error(t, decodeCBOR(bytes.NewReader(append(bytes.Repeat([]byte{0x81}, maxDecodeDepth+1), 0xf6)), &nested, decodeOptions))
+
+	var half float64
+	require.NoError(t, decodeCBOR(bytes.NewReader([]byte{0xf9, 0x3e, 0x00}), &half, decodeOptions))
+	assert.Equal(t, 1.5, half)
+}
+
+func TestCSVAndXML(t *testing.T) {
+	RegisterXML()
+
+	items := []codecItem{
+		{ID: 1, Name: "plain", Price: 1.5},
+		{ID: 2, Name: "with, comma", Tags: []string{"x"}, Price: 2},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, encodeCSV(&buf, items, nil))
+	assert.Equal(t, "id,name,tags,price,data\n1,plain,,1.5,\n2,\"with, comma\",\"[\"\"x\"\"]\",2,\n", buf.String())
+
+	assert.Equal(t, ErrUnencodable, encodeCSV(&bytes.Buffer{}, items[0], nil))
+
+	buf.Reset()
+	require.NoError(t, encodeXML(&buf, items, nil))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<items><codecItem><id>1</id><name>plain</name><price>1.5</price></codecItem>`+
+		`<codecItem><id>2</id><name>with, comma</name><tags>x</tags><price>2</price></codecItem></items>`, buf.String())
+
+	var decodedItems []codecItem
+	require.NoError(t, decodeXML(bytes.NewReader(buf.Bytes()), &decodedItems, decodeOptions))
+	assert.Equal(t, items, decodedItems, "what's sent can be posted back")
+
+	buf.Reset()
+	require.NoError(t, encodeXML(&buf, codecItem{ID: 3, Name: "x", Tags: []string{"a", "b"}}, nil))
+
+	var decoded codecItem
+	require.NoError(t, decodeXML(bytes.NewReader(buf.Bytes()), &decoded, decodeOptions))
+	assert.Equal(t, codecItem{ID: 3, Name: "x", Tags: []string{"a", "b"}}, decoded)
+
+	assert.Equal(t, ErrUnencodable, encodeXML(&bytes.Buffer{}, map[string]interface{}{"a": 1}, nil))
+}
+
+func TestContentNegotiation(t *testing.T) {
+	RegisterXML()
+
+	var received codecItem
+	router := NewRouter()
+	router.Get("/item", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return codecItem{ID: 1, Name: "thing"}, nil
+	})
+	router.Get("/items", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return []codecItem{{ID: 1, Name: "thing"}}, nil
+	})
+	router.Post("/item", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return nil, UnmarshalRequestBody(ctx, r, &received)
+	})
+
+	request := func(method string, target string, accept string, contentType string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	t.Run("Defaults to JSON", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "", "", nil)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+
+		recorder = request(http.MethodGet, "/item", "text/html, */*;q=0.1", "", nil)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("Browsers get JSON unless XML is registered", func(t *testing.T) {
+		defer func(registered []encoderEntry) { encoders = registered }(encoders)
+		encoders = slices.DeleteFunc(slices.Clone(encoders), func(entry encoderEntry) bool {
+			return strings.HasSuffix(entry.mediaType, "/xml")
+		})
+
+		recorder := request(http.MethodGet, "/item", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", nil)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("Wildcards only pick JSON", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "application/cbor;q=0.5, */*", "", nil)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		recorder = request(http.MethodGet, "/items", "text/*", "", nil)
+		assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"), "unless they don't match JSON")
+	})
+
+	t.Run("Pretty JSON", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "application/json; pretty", "", nil)
+		assert.Equal(t, "{\n  \"id\": 1,\n  \"name\": \"thing\",\n  \"price\": 0\n}", recorder.Body.String())
+	})
+
+	t.Run("Picks by quality", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "application/json;q=0.5, application/cbor", "", nil)
+		assert.Equal(t, "application/cbor", recorder.Header().Get("Content-Type"))
+
+		recorder = request(http.MethodGet, "/items", "text/csv", "", nil)
+		assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "id,name,tags,price,data\n1,thing,,0,\n", recorder.Body.String())
+	})
+
+	t.Run("Falls back when the value doesn't fit a format", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "text/csv, application/xml;q=0.5", "", nil)
+		assert.Equal(t, "application/xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("Not acceptable", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/item", "text/csv, image/png, application/problem+json;q=0.1", "", nil)
+		assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+
+		problem := decodeProblem(t, recorder)
+		assert.Equal(t, CodeNotAcceptable, problem.Code)
+		assert.Contains(t, problem.Details["available"], "application/msgpack")
+	})
+
+	t.Run("Decodes by Content-Type", func(t *testing.T) {
+		var body bytes.Buffer
+		require.NoError(t, encodeMsgpack(&body, codecItem{ID: 9, Name: "packed"}, nil))
+
+		recorder := request(http.MethodPost, "/item", "", "application/msgpack", body.Bytes())
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, codecItem{ID: 9, Name: "packed"}, received)
+
+		recorder = request(http.MethodPost, "/item", "", "application/json; charset=utf-8", []byte(`{"id":10}`))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, int64(10), received.ID)
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		recorder := request(http.MethodPost, "/item", "application/problem+json", "application/x-www-form-urlencoded", []byte("id=1"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+		assert.Equal(t, CodeUnsupportedMediaType, decodeProblem(t, recorder).Code)
+	})
+}
+
+func binarySeeds(f *testing.F, encoder Encoder) {
+	for _, value := range []interface{}{
+		nil, true, -1000, uint64(1<<64 - 1), 1.5, "text", []byte{0, 1},
+		map[string]interface{}{"a": 1, "b": []int{2, 3}},
+		codecItem{ID: -70000, Name: "name", Tags: []string{"a"}, Price: 12.25, Data: []byte{0}},
+	} {
+		var buf bytes.Buffer
+		require.NoError(f, encoder(&buf, value, nil))
+		f.Add(buf.Bytes())
+	}
+}
+
+// the binary decoders read whatever clients send, so no input may make them panic or allocate more than its size
+func FuzzReadMsgpack(f *testing.F) {
+	binarySeeds(f, encodeMsgpack)
+	f.Add([]byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var decoded interface{}
+		_ = decodeMsgpack(bytes.NewReader(data), &decoded, decodeOptions)
+
+		var item codecItem
+		_ = decodeMsgpack(bytes.NewReader(data), &item, decodeOptions)
+	})
+}
+
+func FuzzReadCBOR(f *testing.F) {
+	binarySeeds(f, encodeCBOR)
+	f.Add([]byte{0x9a, 0xff, 0xff, 0xff, 0xff, 0x01})
+	f.Add([]byte{0xf9, 0x3e, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var decoded interface{}
+		_ = decodeCBOR(bytes.NewReader(data), &decoded, decodeOptions)
+
+		var item codecItem
+		_ = decodeCBOR(bytes.NewReader(data), &item, decodeOptions)
+	})
+}
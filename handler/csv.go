@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encodeCSV writes a slice as CSV with a header row. Columns of a slice of structs are the JSON names of the fields in
+// order, those of a slice of maps are their keys, sorted, and a slice of anything else has a single value column.
+// Nested objects and arrays are written as JSON.
+func encodeCSV(w io.Writer, value interface{}, _ map[string]string) error {
+	slice := reflect.ValueOf(value)
+	for slice.Kind() == reflect.Pointer && !slice.IsNil() {
+		slice = slice.Elem()
+	}
+
+	if (slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array) || slice.Type().Elem().Kind() == reflect.Uint8 {
+		return ErrUnencodable
+	}
+
+	generic, err := toGeneric(value)
+	if err != nil {
+		return err
+	}
+	rows, _ := generic.([]interface{})
+
+	columns, isObjects := csvColumns(slice.Type().Elem(), rows)
+
+	// an empty slice of maps has nothing to take columns from
+	if len(columns) == 0 {
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		if isObjects {
+			object, _ := row.(map[string]interface{})
+			for i, column := range columns {
+				record[i] = formatScalar(object[column])
+			}
+		} else {
+			record[0] = formatScalar(row)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvColumns(elemType reflect.Type, rows []interface{}) ([]string, bool) {
+	for _, row := range rows {
+		if _, isObject := row.(map[string]interface{}); !isObject && row != nil {
+			return []string{"value"}, false
+		}
+	}
+
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+
+	var columns []string
+	if elemType.Kind() == reflect.Struct {
+		columns = structColumns(elemType, nil)
+	} else if elemType.Kind() != reflect.Map && elemType.Kind() != reflect.Interface {
+		return []string{"value"}, false
+	}
+
+	// keys that aren't fields, e.g. from a custom MarshalJSON, go after the fields
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	var extra []string
+	for _, row := range rows {
+		object, _ := row.(map[string]interface{})
+		for key := range object {
+			if !known[key] {
+				known[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+
+	return append(columns, extra...), true
+}
+
+func structColumns(structType reflect.Type, columns []string) []string {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && tag == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				columns = structColumns(embedded, columns)
+				continue
+			}
+		}
+
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		columns = append(columns, name)
+	}
+
+	return columns
+}
+
+// formatScalar formats a generic value as text, for the formats that have nothing but text
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	nested, _ := json.Marshal(value)
+	return string(nested)
+}
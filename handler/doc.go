@@ -0,0 +1,6 @@
+// Package handler turns functions returning a value and an error into HTTP handlers that negotiate the response format,
+// answer errors with problem details, and log, trace and measure every request.
+//
+// SetDecodeOptions, SetProblemResponses, RegisterEncoder, RegisterDecoder and RegisterXML change package-wide settings
+// without synchronization, so call them during startup, before serving requests.
+package handler
@@ -2,44 +2,157 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/sjohna/go-server-common/errors"
 	"github.com/sjohna/go-server-common/log"
+	"github.com/sjohna/go-server-common/tracing"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type HandlerFunc func(ctx context.Context, r *http.Request) (interface{}, errors.Error)
 
 func Handler(handler HandlerFunc) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		start := time.Now()
+		status := http.StatusOK
+		clientCtx := r.Context()
+
+		requestID := r.Header.Get(log.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(log.RequestIDHeader, requestID)
+
+		ctx := log.WithRequestID(r.Context(), requestID)
+		if r.Pattern != "" {
+			ctx = context.WithValue(ctx, "logger", log.Ctx(ctx).WithField("route", r.Pattern))
+		}
+		remote, _ := tracing.Extract(r.Header)
+		ctx, span := tracing.StartRemote(ctx, spanName(r), tracing.KindServer, remote)
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.route", routeName(r))
+		r = r.WithContext(ctx)
+
+		// with the ResponseWriter, a body over the limit makes the server close the connection instead of reading the
+		// rest of it
+		if decodeOptions.MaxBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, decodeOptions.MaxBytes)
+		}
+
+		defer func() {
+			observeRequest(r, status, start)
+			span.SetAttribute("http.status_code", status)
+			span.End()
+		}()
 
 		ret, err := handler(ctx, r)
 
+		if stream, isStream := ret.(streamer); isStream && err == nil {
+			err = stream.serve(ctx, w, r)
+			if err != nil {
+				if clientWentAway(clientCtx) {
+					err = errors.WrapClientCanceled(err, "Client went away while streaming response")
+					status = StatusClientClosedRequest
+				}
+
+				log.LogError(ctx, err, "Error while streaming response")
+				span.SetError(err)
+			}
+
+			return
+		}
+
+		response := asResponse(ret)
+
+		var contentType string
+		var body []byte
+		if err == nil && response.hasBody() {
+			contentType, body, err = encodeResponse(r, response.Body)
+		}
+
 		if err != nil {
-			if err.Warning() {
-				log.Ctx(ctx).WithError(err).Warn("Error returned from handler func")
-			} else {
-				log.Ctx(ctx).WithError(err).Error("Error returned from handler func")
+			// nobody is listening for the response, so don't bother writing one
+			if clientWentAway(clientCtx) {
+				err = errors.WrapClientCanceled(err, "Client went away before the response was written")
+				status = StatusClientClosedRequest
+
+				log.LogError(ctx, err, "Error returned from handler func")
+				span.SetError(err)
+				return
 			}
 
-			httpResponseCode := http.StatusBadRequest
-			if err.Internal() {
-				httpResponseCode = http.StatusInternalServerError
+			log.LogError(ctx, err, "Error returned from handler func")
+			span.SetError(err)
+
+			// the client is still there, so whatever was canceled, it wasn't the client
+			status = StatusCode(err)
+			if status == StatusClientClosedRequest {
+				status = http.StatusInternalServerError
 			}
 
-			http.Error(w, err.Error(), httpResponseCode)
+			writeProblem(ctx, w, r, err, status)
 			return
 		}
 
-		if ret != nil {
-			err := RespondJSON(ctx, w, ret)
+		status = response.status()
+		response.writeHeaders(w)
+
+		if response.hasBody() {
+			err := writeResponse(w, status, contentType, body)
 			if err != nil {
-				log.Ctx(ctx).WithError(err).Error("Error writing JSON response to handler!!!!")
+				log.Ctx(ctx).WithError(err).Error("Error writing response to handler!!!!")
+				span.SetError(err)
+
+				// the status already went out, but the response didn't, so don't count it as a success
+				status = http.StatusInternalServerError
+				if clientWentAway(clientCtx) {
+					status = StatusClientClosedRequest
+				}
 			}
 
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		w.WriteHeader(status)
+	}
+}
+
+// clientWentAway is only true for a canceled request. A deadline, e.g. from timeout middleware, is the server giving up
+// on the request, which still gets a response.
+func clientWentAway(clientCtx context.Context) bool {
+	return clientCtx.Err() == context.Canceled
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// request IDs from clients end up in logs and other services' requests, so only short, plain ones are kept
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+
+	for _, c := range requestID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// patterns can already start with the method, e.g. "GET /items/{id}"
+func spanName(r *http.Request) string {
+	route := routeName(r)
+	if strings.HasPrefix(route, r.Method+" ") {
+		return "HTTP " + route
 	}
+
+	return "HTTP " + r.Method + " " + route
 }
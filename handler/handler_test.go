@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorResponses(t *testing.T) {
+	failing := http.HandlerFunc(Handler(func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return nil, errors.NewInput("Bad input")
+	}))
+
+	request := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+
+		recorder := httptest.NewRecorder()
+		failing.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	t.Run("Plain text unless JSON is accepted", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "text/html"} {
+			recorder := request(accept)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, "Bad input\n", recorder.Body.String())
+		}
+	})
+
+	t.Run("Problem when JSON is accepted", func(t *testing.T) {
+		for _, accept := range []string{"application/json", "text/html, application/problem+json;q=0.5"} {
+			recorder := request(accept)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, "Bad input", decodeProblem(t, recorder).Title)
+		}
+	})
+
+	t.Run("Problem always when configured", func(t *testing.T) {
+		defer SetProblemResponses(false)
+		SetProblemResponses(true)
+
+		assert.Equal(t, "application/problem+json", request("").Header().Get("Content-Type"))
+	})
+}
+
+func TestCancellation(t *testing.T) {
+	canceled := http.HandlerFunc(Handler(func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return nil, errors.Wrap(fmt.Errorf("downstream call: %w", context.Canceled), "Error loading thing")
+	}))
+
+	t.Run("Internal cancellation on a live request is a server error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "application/json")
+
+		recorder := httptest.NewRecorder()
+		canceled.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, http.StatusInternalServerError, decodeProblem(t, recorder).Status)
+	})
+
+	t.Run("Nothing written once the client has gone away", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		recorder := httptest.NewRecorder()
+		canceled.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		assert.False(t, recorder.Flushed)
+		assert.Empty(t, recorder.Header().Get("Content-Type"))
+		assert.Empty(t, recorder.Body.String())
+	})
+}
+
+func TestMiddlewareDeadline(t *testing.T) {
+	router := NewRouter()
+	router.UseHTTP(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Millisecond)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Get("/slow", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		<-ctx.Done()
+		return nil, errors.WrapDBError(ctx.Err(), "Query timed out")
+	})
+
+	recorder := serve(router, http.MethodGet, "/slow")
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	assert.Equal(t, errors.CodeTimeout, decodeProblem(t, recorder).Code)
+}
+
+func TestStatusCode(t *testing.T) {
+	t.Run("Wrapping keeps the status of the code", func(t *testing.T) {
+		notFound := errors.FromCode(CodeRouteNotFound, errors.Params{"path": "/x"})
+		assert.Equal(t, http.StatusNotFound, StatusCode(notFound))
+		assert.Equal(t, http.StatusNotFound, StatusCode(errors.Wrap(notFound, "outer")))
+		assert.Equal(t, http.StatusNotFound, StatusCode(errors.Wrap(errors.Wrap(notFound, "middle"), "outer")))
+	})
+
+	t.Run("Wrapping without a code is internal", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, StatusCode(errors.NewInput("bad")))
+		assert.Equal(t, http.StatusInternalServerError, StatusCode(errors.Wrap(errors.NewInput("bad"), "outer")))
+	})
+
+	t.Run("Timeouts in dependencies stay gateway timeouts when wrapped", func(t *testing.T) {
+		timeout := errors.WrapThirdParty(context.DeadlineExceeded, "slow dependency")
+		assert.Equal(t, http.StatusGatewayTimeout, StatusCode(timeout))
+		assert.Equal(t, http.StatusGatewayTimeout, StatusCode(errors.Wrap(timeout, "outer")))
+		assert.Equal(t, http.StatusServiceUnavailable, StatusCode(errors.Wrap(context.DeadlineExceeded, "timed out")))
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := http.HandlerFunc(Handler(func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		seen = log.RequestID(ctx)
+		return nil, nil
+	}))
+
+	request := func(requestID string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(log.RequestIDHeader, requestID)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		assert.Equal(t, seen, recorder.Header().Get(log.RequestIDHeader))
+		return seen
+	}
+
+	assert.Equal(t, "abc-123_x.y", request("abc-123_x.y"))
+	assert.Len(t, request(""), 16)
+
+	for _, invalid := range []string{strings.Repeat("a", 129), "abc def", "abc\nforged=1", "ü"} {
+		assert.NotEqual(t, invalid, request(invalid))
+		assert.Len(t, seen, 16)
+	}
+}
+
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("broken pipe")
+}
+
+func TestWriteFailureStatus(t *testing.T) {
+	handler := http.HandlerFunc(Handler(func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return "body", nil
+	}))
+
+	failed := requestsTotal.With("unmatched", http.MethodPut, "500")
+	before := failed.Value()
+
+	handler.ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodPut, "/", nil))
+	assert.Equal(t, 1.0, failed.Value()-before)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptedLanguages returns the language tags from the Accept-Language header, most preferred first
+func AcceptedLanguages(r *http.Request) []string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return nil
+	}
+
+	type weightedLanguage struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []weightedLanguage
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if qValue, hasQ := strings.CutPrefix(strings.TrimSpace(params), "q="); hasQ {
+			parsed, err := strconv.ParseFloat(qValue, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, weightedLanguage{tag, quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, language := range languages {
+		tags[i] = language.tag
+	}
+
+	return tags
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/sjohna/go-server-common/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var requestsTotal = metrics.NewCounterVec("http_requests_total", "HTTP requests handled, by route, method and status.", "route", "method", "status")
+var requestDuration = metrics.NewHistogramVec("http_request_duration_seconds", "Time taken to handle HTTP requests, by route and method.", nil, "route", "method")
+
+func observeRequest(r *http.Request, status int, start time.Time) {
+	route := routeName(r)
+	requestsTotal.With(route, r.Method, strconv.Itoa(status)).Inc()
+	requestDuration.With(route, r.Method).Observe(time.Since(start).Seconds())
+}
+
+// the pattern rather than the path, so the number of series and span names stays bounded
+func routeName(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+
+	return r.Pattern
+}
@@ -0,0 +1,16 @@
+package handler
+
+// Middleware wraps a HandlerFunc. Unlike HTTPMiddleware it sees the value and error the handler returns, so it can
+// check or transform them before they're written.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain combines middleware into one, with the first being the outermost
+func Chain(middleware ...Middleware) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+
+		return next
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+				order = append(order, name)
+				return next(ctx, r)
+			}
+		}
+	}
+
+	requireToken := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+			if r.Header.Get("Authorization") == "" {
+				return nil, errors.NewInput("Missing token")
+			}
+
+			return next(ctx, r)
+		}
+	}
+
+	wrapResult := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+			value, err := next(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+
+			return map[string]interface{}{"data": value}, nil
+		}
+	}
+
+	router := NewRouter()
+	router.Use(trace("global"))
+	router.Get("/public", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		order = append(order, "handler")
+		return "hello", nil
+	}, Chain(trace("route"), wrapResult))
+	router.Get("/private", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return "secret", nil
+	}, requireToken)
+
+	t.Run("Global runs before per-route, in order", func(t *testing.T) {
+		order = nil
+		recorder := serve(router, http.MethodGet, "/public")
+
+		assert.Equal(t, []string{"global", "route", "handler"}, order)
+		assert.JSONEq(t, `{"data":"hello"}`, recorder.Body.String())
+	})
+
+	t.Run("Middleware can return errors", func(t *testing.T) {
+		order = nil
+		recorder := serve(router, http.MethodGet, "/private")
+
+		assert.Equal(t, []string{"global"}, order)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, "Missing token", decodeProblem(t, recorder).Title)
+	})
+}
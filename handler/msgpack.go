@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/ugorji/go/codec"
+	"io"
+	"reflect"
+)
+
+// MessagePack follows the json tags of the value, so it needs no tags of its own, but unlike JSON, []byte fields are
+// binary. UseNumber doesn't apply, since numbers keep their own types.
+
+var msgpackHandle = newMsgpackHandle(false)
+var msgpackStrictHandle = newMsgpackHandle(true)
+
+func newMsgpackHandle(disallowUnknownFields bool) *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{WriteExt: true}
+	handle.TypeInfos = codec.NewTypeInfos([]string{"json"})
+	handle.Canonical = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	handle.RawToString = true
+	handle.ErrorIfNoField = disallowUnknownFields
+
+	// a bogus length can't make the decoder allocate more than this up front, or recurse without end
+	handle.MaxInitLen = 1 << 12
+	handle.MaxDepth = maxDecodeDepth
+
+	return handle
+}
+
+func encodeMsgpack(w io.Writer, value interface{}, _ map[string]string) error {
+	return codec.NewEncoder(w, msgpackHandle).Encode(value)
+}
+
+func decodeMsgpack(r io.Reader, value interface{}, options DecodeOptions) error {
+	data, err := readBinary(r)
+	if err != nil {
+		return err
+	}
+
+	handle := msgpackHandle
+	if options.DisallowUnknownFields {
+		handle = msgpackStrictHandle
+	}
+
+	decoder := codec.NewDecoderBytes(data, handle)
+	if err := decoder.Decode(value); err != nil {
+		return binaryError(err)
+	}
+
+	if options.DisallowTrailingData && decoder.NumBytesRead() < len(data) {
+		return errTrailingData
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"github.com/sjohna/go-server-common/errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	CodeNotAcceptable        = "not_acceptable"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+)
+
+func init() {
+	errors.Register(
+		errors.Definition{
+			Code:        CodeNotAcceptable,
+			HTTPStatus:  http.StatusNotAcceptable,
+			Severity:    errors.SeverityInfo,
+			Origin:      errors.OriginInput,
+			Message:     "The response can't be encoded as {accept}",
+			Description: "None of the media types in the Accept header are available for the response. The available detail lists the ones that are.",
+		},
+		errors.Definition{
+			Code:        CodeUnsupportedMediaType,
+			HTTPStatus:  http.StatusUnsupportedMediaType,
+			Severity:    errors.SeverityInfo,
+			Origin:      errors.OriginInput,
+			Message:     "Request bodies of type {contentType} aren't supported",
+			Description: "The Content-Type of the request body has no registered decoder.",
+		},
+	)
+}
+
+type acceptEntry struct {
+	mediaType string
+	params    map[string]string
+	quality   float64
+}
+
+// parseAccept returns the entries of an Accept header, most preferred first. Between entries of equal quality, more
+// specific ones win, then earlier ones.
+func parseAccept(header string) []acceptEntry {
+	var entries []acceptEntry
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		entry := acceptEntry{mediaType, map[string]string{}, 1.0}
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			if key == "q" {
+				quality, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					quality = 0
+				}
+				entry.quality = quality
+			} else if key != "" {
+				entry.params[key] = value
+			}
+		}
+
+		if entry.quality > 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].quality != entries[j].quality {
+			return entries[i].quality > entries[j].quality
+		}
+
+		return specificity(entries[i].mediaType) > specificity(entries[j].mediaType)
+	})
+
+	return entries
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+
+	return 2
+}
+
+func (e acceptEntry) matches(mediaType string) bool {
+	if e.mediaType == "*/*" || e.mediaType == mediaType {
+		return true
+	}
+
+	prefix, isWildcard := strings.CutSuffix(e.mediaType, "*")
+	return isWildcard && strings.HasPrefix(mediaType, prefix)
+}
+
+type negotiated struct {
+	encoder encoderEntry
+	params  map[string]string
+}
+
+// negotiate lists the encoders acceptable for the request's Accept header, most preferred first. No Accept header
+// means JSON, and so does a wildcard that matches it, so other formats are only picked when the client names them,
+// e.g. not application/xml for a browser's text/html, */*;q=0.8.
+func negotiate(r *http.Request) []negotiated {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		return []negotiated{{encoders[0], nil}}
+	}
+
+	var candidates []negotiated
+	for _, entry := range parseAccept(accept) {
+		if specificity(entry.mediaType) < 2 && entry.matches(encoders[0].mediaType) {
+			candidates = append(candidates, negotiated{encoders[0], entry.params})
+			continue
+		}
+
+		for _, encoder := range encoders {
+			if entry.matches(encoder.mediaType) {
+				candidates = append(candidates, negotiated{encoder, entry.params})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// ErrUnencodable is returned by encoders for values they can't represent, like CSV for anything but a slice. The next
+// acceptable encoder gets a try instead.
+var ErrUnencodable = stderrors.New("value can't be represented in this format")
+
+func notAcceptable(accept string) errors.Error {
+	available := make([]string, len(encoders))
+	for i, encoder := range encoders {
+		available[i] = encoder.mediaType
+	}
+
+	return errors.FromCode(CodeNotAcceptable, errors.Params{"accept": accept}).
+		WithPublic("available", available)
+}
+
+func requestDecoder(r *http.Request) (Decoder, errors.Error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return decoders["application/json"], nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if decoder, exists := decoders[mediaType]; exists {
+			return decoder, nil
+		}
+	}
+
+	return nil, errors.FromCode(CodeUnsupportedMediaType, errors.Params{"contentType": contentType})
+}
+
+// encodeResponse encodes value for the request's Accept header, without writing anything yet so an error can still
+// become a problem response
+func encodeResponse(r *http.Request, value interface{}) (string, []byte, errors.Error) {
+	for _, candidate := range negotiate(r) {
+		var body bytes.Buffer
+		err := candidate.encoder.encode(&body, value, candidate.params)
+		if err == ErrUnencodable {
+			continue
+		}
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Error encoding "+candidate.encoder.mediaType+" response")
+		}
+
+		return candidate.encoder.contentType, body.Bytes(), nil
+	}
+
+	return "", nil, notAcceptable(strings.Join(r.Header.Values("Accept"), ","))
+}
+
+// Respond writes value in the format the client asked for with its Accept header
+func Respond(ctx context.Context, w http.ResponseWriter, r *http.Request, value interface{}) errors.Error {
+	contentType, body, err := encodeResponse(r, value)
+	if err != nil {
+		return err
+	}
+
+	return writeResponse(w, http.StatusOK, contentType, body)
+}
+
+func writeResponse(w http.ResponseWriter, status int, contentType string, body []byte) errors.Error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+
+	_, err := w.Write(body)
+	if err != nil {
+		return errors.Wrap(err, "Error writing response")
+	}
+
+	return nil
+}
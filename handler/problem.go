@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"net/http"
+)
+
+// Problem is the RFC 7807 body written for errors returned from handler funcs
+type Problem struct {
+	Type    string                 `json:"type,omitempty"`
+	Title   string                 `json:"title"`
+	Status  int                    `json:"status"`
+	Code    string                 `json:"code,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// the title is localized to the first of locales with a translation for the error's code
+func NewProblem(err errors.Error, locales ...string) Problem {
+	problem := Problem{
+		Title:  err.Error(),
+		Status: StatusCode(err),
+	}
+
+	if appErr, isAppErr := errors.AsApplicationError(err); isAppErr {
+		problem.Title = appErr.LocalizedMessage(locales...)
+		problem.Code = appErr.Code
+		problem.Details = appErr.PublicDetails()
+
+		if definition, isDefined := errors.Lookup(appErr.Code); isDefined {
+			problem.Type = definition.DocURL
+		}
+	}
+
+	return problem
+}
+
+// nginx's non-standard status for a client closing the connection before the response was written
+const StatusClientClosedRequest = 499
+
+func StatusCode(err errors.Error) int {
+	if appErr, isAppErr := errors.AsApplicationError(err); isAppErr {
+		if appErr.Code == errors.CodeTimeout && timedOutInDependency(appErr) {
+			return http.StatusGatewayTimeout
+		}
+
+		if definition, isDefined := errors.Lookup(appErr.Code); isDefined && definition.HTTPStatus != 0 {
+			return definition.HTTPStatus
+		}
+	}
+
+	if err.Internal() {
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusBadRequest
+}
+
+var alwaysWriteProblems = false
+
+// SetProblemResponses chooses whether error responses are always application/problem+json. By default they only are
+// for requests that accept application/problem+json or application/json, and other clients get the message as plain
+// text.
+func SetProblemResponses(always bool) {
+	alwaysWriteProblems = always
+}
+
+func acceptsProblem(r *http.Request) bool {
+	if alwaysWriteProblems {
+		return true
+	}
+
+	for _, entry := range parseAccept(r.Header.Get("Accept")) {
+		if entry.mediaType == "application/problem+json" || entry.mediaType == "application/json" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrapping a dependency's timeout keeps the code, so look for where it came from
+func timedOutInDependency(err *errors.ApplicationError) bool {
+	for err != nil && err.Code == errors.CodeTimeout {
+		if err.Origin == errors.OriginThirdParty {
+			return true
+		}
+
+		err, _ = errors.AsApplicationError(err.Inner)
+	}
+
+	return false
+}
+
+func writeProblem(ctx context.Context, w http.ResponseWriter, r *http.Request, err errors.Error, status int) {
+	problem := NewProblem(err, AcceptedLanguages(r)...)
+	problem.Status = status
+
+	if !acceptsProblem(r) {
+		http.Error(w, problem.Title, problem.Status)
+		return
+	}
+
+	body, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		log.Ctx(ctx).WithError(errors.Wrap(marshalErr, "Error marshalling problem response")).Error("Failed to marshal problem response")
+		http.Error(w, problem.Title, problem.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+
+	_, writeErr := w.Write(body)
+	if writeErr != nil {
+		log.Ctx(ctx).WithError(errors.Wrap(writeErr, "Error writing problem response")).Error("Failed to write problem response")
+	}
+}
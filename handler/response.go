@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response is returned from a handler func instead of a plain value to control the status, headers and cookies of a
+// successful response. Body is encoded like any returned value, and a nil Body writes no body. Neither does a status
+// that can't have one, like 204 or 304, so Body is dropped for those. When the handler func also returns an error, the
+// Response is ignored entirely, headers and cookies included, and only the error response is written.
+type Response struct {
+	Status  int // 0 for 200, or 204 if Body is nil
+	Headers http.Header
+	Cookies []*http.Cookie
+	Body    interface{}
+}
+
+const (
+	CacheNoStore   = "no-store"
+	CacheNoCache   = "no-cache"
+	CachePrivate   = "private"
+	CachePublic    = "public"
+	CacheImmutable = "immutable"
+)
+
+// MaxAge is the max-age cache directive
+func MaxAge(d time.Duration) string {
+	return "max-age=" + strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func NewResponse(status int, body interface{}) *Response {
+	return &Response{
+		Status:  status,
+		Headers: http.Header{},
+		Body:    body,
+	}
+}
+
+// Created is a 201 with the URL of the new resource in the Location header
+func Created(location string, body interface{}) *Response {
+	return NewResponse(http.StatusCreated, body).WithHeader("Location", location)
+}
+
+// Accepted is a 202, optionally with the URL to check the status of the work at
+func Accepted(location string, body interface{}) *Response {
+	response := NewResponse(http.StatusAccepted, body)
+	if location != "" {
+		response.WithHeader("Location", location)
+	}
+
+	return response
+}
+
+func (r *Response) WithHeader(key string, value string) *Response {
+	if r.Headers == nil {
+		r.Headers = http.Header{}
+	}
+
+	r.Headers.Add(key, value)
+	return r
+}
+
+func (r *Response) WithCookie(cookie *http.Cookie) *Response {
+	r.Cookies = append(r.Cookies, cookie)
+	return r
+}
+
+// WithCacheControl sets the Cache-Control header, e.g. WithCacheControl(CachePublic, MaxAge(time.Hour))
+func (r *Response) WithCacheControl(directives ...string) *Response {
+	if r.Headers == nil {
+		r.Headers = http.Header{}
+	}
+
+	r.Headers.Set("Cache-Control", strings.Join(directives, ", "))
+	return r
+}
+
+// asResponse turns whatever a handler func returned into a Response
+func asResponse(value interface{}) *Response {
+	switch v := value.(type) {
+	case *Response:
+		if v == nil {
+			return &Response{}
+		}
+		return v
+	case Response:
+		return &v
+	}
+
+	return &Response{Body: value}
+}
+
+func (r *Response) status() int {
+	switch {
+	case r.Status != 0:
+		return r.Status
+	case r.Body == nil:
+		return http.StatusNoContent
+	}
+
+	return http.StatusOK
+}
+
+// hasBody is false when there's no Body, or the status doesn't allow one
+func (r *Response) hasBody() bool {
+	if r.Body == nil {
+		return false
+	}
+
+	status := r.status()
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func (r *Response) writeHeaders(w http.ResponseWriter) {
+	for key, values := range r.Headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	for _, cookie := range r.Cookies {
+		http.SetCookie(w, cookie)
+	}
+}
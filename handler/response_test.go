@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponse(t *testing.T) {
+	router := NewRouter()
+	router.Post("/items", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return Created("/items/5", map[string]int{"id": 5}).
+			WithCookie(&http.Cookie{Name: "last-created", Value: "5"}), nil
+	})
+	router.Post("/jobs", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return Accepted("/jobs/1", nil), nil
+	})
+	router.Get("/config", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return NewResponse(0, "cached").
+			WithHeader("X-Version", "3").
+			WithCacheControl(CachePublic, MaxAge(time.Hour)), nil
+	})
+	router.Get("/nothing", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		var response *Response
+		return response, nil
+	})
+	router.Get("/unchanged", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return NewResponse(http.StatusNotModified, "ignored").WithHeader("ETag", `"v1"`), nil
+	})
+	router.Get("/failing", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return NewResponse(http.StatusOK, "ignored").WithHeader("X-Version", "3"), errors.NewInput("Bad input")
+	})
+
+	t.Run("Created", func(t *testing.T) {
+		recorder := serve(router, http.MethodPost, "/items")
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, "/items/5", recorder.Header().Get("Location"))
+		assert.Equal(t, "last-created=5", recorder.Header().Get("Set-Cookie"))
+		assert.JSONEq(t, `{"id":5}`, recorder.Body.String())
+	})
+
+	t.Run("Accepted without a body", func(t *testing.T) {
+		recorder := serve(router, http.MethodPost, "/jobs")
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		assert.Equal(t, "/jobs/1", recorder.Header().Get("Location"))
+		assert.Empty(t, recorder.Body.String())
+	})
+
+	t.Run("Headers and cache directives", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/config")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "3", recorder.Header().Get("X-Version"))
+		assert.Equal(t, "public, max-age=3600", recorder.Header().Get("Cache-Control"))
+		assert.Equal(t, `"cached"`, recorder.Body.String())
+	})
+
+	t.Run("Nil response", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve(router, http.MethodGet, "/nothing").Code)
+	})
+
+	t.Run("No body for statuses without one", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/unchanged")
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, `"v1"`, recorder.Header().Get("ETag"))
+		assert.Empty(t, recorder.Header().Get("Content-Type"))
+		assert.Empty(t, recorder.Body.String())
+	})
+
+	t.Run("Ignored along with an error", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/failing")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("X-Version"))
+	})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	CodeRouteNotFound    = "route_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInvalidPathParam = "invalid_path_param"
+)
+
+func init() {
+	errors.Register(
+		errors.Definition{
+			Code:        CodeRouteNotFound,
+			HTTPStatus:  http.StatusNotFound,
+			Severity:    errors.SeverityInfo,
+			Origin:      errors.OriginInput,
+			Message:     "No route matches {path}",
+			Description: "The path of the request doesn't match any registered route.",
+		},
+		errors.Definition{
+			Code:        CodeMethodNotAllowed,
+			HTTPStatus:  http.StatusMethodNotAllowed,
+			Severity:    errors.SeverityInfo,
+			Origin:      errors.OriginInput,
+			Message:     "{method} is not allowed for {path}",
+			Description: "The path matches a route, but not for the request's method. The Allow header lists the methods that are.",
+		},
+		errors.Definition{
+			Code:        CodeInvalidPathParam,
+			HTTPStatus:  http.StatusBadRequest,
+			Severity:    errors.SeverityInfo,
+			Origin:      errors.OriginInput,
+			Message:     "Path parameter {param} is invalid",
+			Description: "A path parameter couldn't be parsed as the type the route expects.",
+		},
+	)
+}
+
+// HTTPMiddleware wraps the raw handler of a route, for things that work on bytes and headers rather than values
+type HTTPMiddleware func(http.Handler) http.Handler
+
+// Router registers HandlerFuncs on a ServeMux by method and pattern, using the ServeMux pattern syntax for the path,
+// e.g. "/items/{id}". Unmatched paths and methods get problem responses like any other error.
+type Router struct {
+	mux            *http.ServeMux
+	prefix         string
+	middleware     []Middleware
+	httpMiddleware []HTTPMiddleware
+}
+
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Group returns a router that registers on the same mux with prefix added to every pattern. It starts with the
+// middleware of rt, and middleware added to the group doesn't affect rt.
+func (rt *Router) Group(prefix string) *Router {
+	return &Router{
+		mux:            rt.mux,
+		prefix:         rt.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware:     append([]Middleware(nil), rt.middleware...),
+		httpMiddleware: append([]HTTPMiddleware(nil), rt.httpMiddleware...),
+	}
+}
+
+// Use adds middleware to routes registered after it, outermost first. It runs inside any HTTPMiddleware.
+func (rt *Router) Use(middleware ...Middleware) {
+	rt.middleware = append(rt.middleware, middleware...)
+}
+
+// UseHTTP adds middleware to routes registered after it, outermost first
+func (rt *Router) UseHTTP(middleware ...HTTPMiddleware) {
+	rt.httpMiddleware = append(rt.httpMiddleware, middleware...)
+}
+
+// Handle registers handler for method and pattern, wrapped in the router's middleware and then the route's own. An
+// empty method matches every method.
+func (rt *Router) Handle(method string, pattern string, handler HandlerFunc, middleware ...Middleware) {
+	handler = Chain(middleware...)(handler)
+	handler = Chain(rt.middleware...)(handler)
+
+	var h http.Handler = http.HandlerFunc(Handler(handler))
+	for i := len(rt.httpMiddleware) - 1; i >= 0; i-- {
+		h = rt.httpMiddleware[i](h)
+	}
+
+	fullPattern := rt.prefix + pattern
+	if method != "" {
+		fullPattern = method + " " + fullPattern
+	}
+
+	rt.mux.Handle(fullPattern, h)
+}
+
+func (rt *Router) Get(pattern string, handler HandlerFunc, middleware ...Middleware) {
+	rt.Handle(http.MethodGet, pattern, handler, middleware...)
+}
+
+func (rt *Router) Post(pattern string, handler HandlerFunc, middleware ...Middleware) {
+	rt.Handle(http.MethodPost, pattern, handler, middleware...)
+}
+
+func (rt *Router) Put(pattern string, handler HandlerFunc, middleware ...Middleware) {
+	rt.Handle(http.MethodPut, pattern, handler, middleware...)
+}
+
+func (rt *Router) Patch(pattern string, handler HandlerFunc, middleware ...Middleware) {
+	rt.Handle(http.MethodPatch, pattern, handler, middleware...)
+}
+
+func (rt *Router) Delete(pattern string, handler HandlerFunc, middleware ...Middleware) {
+	rt.Handle(http.MethodDelete, pattern, handler, middleware...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, pattern := rt.mux.Handler(r)
+	if pattern != "" {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// let the mux decide between 404, 405 and redirects, then answer in our own format
+	recorder := &headerRecorder{header: http.Header{}}
+	h.ServeHTTP(recorder, r)
+
+	var err errors.Error
+	switch recorder.status {
+	case http.StatusNotFound:
+		err = errors.FromCode(CodeRouteNotFound, errors.Params{"path": r.URL.Path})
+	case http.StatusMethodNotAllowed:
+		w.Header().Set("Allow", recorder.header.Get("Allow"))
+		err = errors.FromCode(CodeMethodNotAllowed, errors.Params{"method": r.Method, "path": r.URL.Path})
+	default:
+		for key, values := range recorder.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.status)
+		return
+	}
+
+	Handler(func(_ context.Context, _ *http.Request) (interface{}, errors.Error) {
+		return nil, err
+	})(w, r)
+}
+
+// headerRecorder keeps the status and headers written by the mux's own handlers, and throws the body away
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (h *headerRecorder) Header() http.Header {
+	return h.header
+}
+
+func (h *headerRecorder) Write(body []byte) (int, error) {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+
+	return len(body), nil
+}
+
+func (h *headerRecorder) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+}
+
+// PathString returns a path parameter of the matched route, e.g. id for "/items/{id}"
+func PathString(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
+
+func PathInt64(r *http.Request, name string) (int64, errors.Error) {
+	value, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		return 0, errors.WrapCode(err, CodeInvalidPathParam, errors.Params{"param": name}).
+			WithPublic("param", name)
+	}
+
+	return value, nil
+}
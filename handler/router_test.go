@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(handler http.Handler, method string, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("Accept", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func decodeProblem(t *testing.T, recorder *httptest.ResponseRecorder) Problem {
+	var problem Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	return problem
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	router.Get("/items/{id}", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		id, err := PathInt64(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"id": id, "route": r.Pattern}, nil
+	})
+	router.Delete("/items/{id}", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return nil, nil
+	})
+
+	var groupMiddlewareRan bool
+	api := router.Group("/api/")
+	api.UseHTTP(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			groupMiddlewareRan = true
+			next.ServeHTTP(w, r)
+		})
+	})
+	api.Get("/users/{name}", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return PathString(r, "name"), nil
+	})
+
+	t.Run("Matches method and pattern", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/items/12")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"id":12,"route":"GET /items/{id}"}`, recorder.Body.String())
+
+		assert.Equal(t, http.StatusNoContent, serve(router, http.MethodDelete, "/items/12").Code)
+		assert.False(t, groupMiddlewareRan, "group middleware doesn't apply to the parent")
+	})
+
+	t.Run("Invalid path parameter", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/items/twelve")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		problem := decodeProblem(t, recorder)
+		assert.Equal(t, CodeInvalidPathParam, problem.Code)
+		assert.Equal(t, "id", problem.Details["param"])
+	})
+
+	t.Run("Groups add prefix and middleware", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/api/users/ada")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"ada"`, recorder.Body.String())
+		assert.True(t, groupMiddlewareRan)
+	})
+
+	t.Run("Method not allowed", func(t *testing.T) {
+		recorder := serve(router, http.MethodPost, "/items/12")
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+		assert.Equal(t, "DELETE, GET, HEAD", recorder.Header().Get("Allow"))
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, CodeMethodNotAllowed, decodeProblem(t, recorder).Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/nothing")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "No route matches /nothing", decodeProblem(t, recorder).Title)
+	})
+}
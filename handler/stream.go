@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamer is a value returned from a handler func that writes the response itself, as it's produced. Once it has
+// started, errors can't change the status anymore, so they're written into the stream.
+type streamer interface {
+	serve(ctx context.Context, w http.ResponseWriter, r *http.Request) errors.Error
+}
+
+// startStream writes the headers of a streamed response. Streams usually outlive the server's write timeout, so it's
+// lifted for this response.
+func startStream(w http.ResponseWriter, contentType string) *http.ResponseController {
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep proxies like nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	_ = controller.Flush()
+
+	return controller
+}
+
+// writeStreamErr wraps an error from writing to the client, which is almost always the client having gone away
+func writeStreamErr(ctx context.Context, err error) errors.Error {
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "Client went away while streaming response")
+	}
+
+	return errors.Wrap(err, "Error writing streamed response")
+}
+
+// Event is a server-sent event. Data that isn't a string is sent as JSON.
+type Event struct {
+	ID   string
+	Name string
+	Data interface{}
+}
+
+// EventWriter sends events to one client. It's safe to use from several goroutines.
+type EventWriter struct {
+	ctx         context.Context
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	lastEventID string
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// LastEventID is the ID of the last event the client got before reconnecting, for resuming where it left off. It's
+// empty on the first connection.
+func (e *EventWriter) LastEventID() string {
+	return e.lastEventID
+}
+
+func (e *EventWriter) Send(event Event) errors.Error {
+	var data string
+	switch v := event.Data.(type) {
+	case string:
+		data = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "Error marshalling event data")
+		}
+		data = string(encoded)
+	}
+
+	var builder strings.Builder
+	if event.ID != "" {
+		builder.WriteString("id: " + sanitizeEventField(event.ID) + "\n")
+	}
+	if event.Name != "" {
+		builder.WriteString("event: " + sanitizeEventField(event.Name) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+
+	return e.write(builder.String())
+}
+
+// Retry tells the client how long to wait before reconnecting if the connection drops
+func (e *EventWriter) Retry(d time.Duration) errors.Error {
+	return e.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// Comment sends a line clients ignore, which keeps idle connections from being closed by proxies
+func (e *EventWriter) Comment(text string) errors.Error {
+	return e.write(": " + sanitizeEventField(text) + "\n\n")
+}
+
+func (e *EventWriter) write(text string) errors.Error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed {
+		return errors.New("Event stream has already ended")
+	}
+	if e.ctx.Err() != nil {
+		return writeStreamErr(e.ctx, e.ctx.Err())
+	}
+
+	_, err := e.w.Write([]byte(text))
+	if err == nil {
+		err = e.controller.Flush()
+	}
+	if err != nil {
+		return writeStreamErr(e.ctx, err)
+	}
+
+	return nil
+}
+
+// newlines would end the field early and let the rest be read as other fields
+func sanitizeEventField(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// SSEStream is returned from a handler func to send server-sent events. The stream ends when Run returns or the client
+// disconnects, which cancels the context passed to Run.
+type SSEStream struct {
+	Heartbeat time.Duration // interval of comments sent to keep the connection open, 0 for none
+	Retry     time.Duration // reconnection delay sent to the client at the start, 0 to leave it to the client
+	Run       func(ctx context.Context, events *EventWriter) errors.Error
+}
+
+func SSE(run func(ctx context.Context, events *EventWriter) errors.Error) *SSEStream {
+	return &SSEStream{
+		Heartbeat: 15 * time.Second,
+		Retry:     0,
+		Run:       run,
+	}
+}
+
+func (s *SSEStream) serve(ctx context.Context, w http.ResponseWriter, r *http.Request) errors.Error {
+	events := &EventWriter{
+		ctx:         ctx,
+		w:           w,
+		controller:  startStream(w, "text/event-stream"),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+	}
+
+	if s.Retry > 0 {
+		if err := events.Retry(s.Retry); err != nil {
+			return err
+		}
+	}
+
+	// nothing can be written once the handler has returned, so wait for the heartbeat to stop
+	defer func() {
+		events.mutex.Lock()
+		events.closed = true
+		events.mutex.Unlock()
+	}()
+
+	if s.Heartbeat > 0 {
+		stopHeartbeat := make(chan struct{})
+		var heartbeat sync.WaitGroup
+		defer heartbeat.Wait()
+		defer close(stopHeartbeat)
+
+		heartbeat.Add(1)
+		go func() {
+			defer heartbeat.Done()
+
+			ticker := time.NewTicker(s.Heartbeat)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					_ = events.Comment("heartbeat")
+				case <-stopHeartbeat:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	err := s.Run(ctx, events)
+	if err != nil && ctx.Err() == nil {
+		_ = events.Send(Event{Name: "error", Data: NewProblem(err, AcceptedLanguages(r)...)})
+	}
+
+	return err
+}
+
+// NDJSONStream is returned from a handler func to stream values as newline-delimited JSON. An error from the values
+// ends the stream with a final {"error": problem} line.
+type NDJSONStream struct {
+	FlushInterval time.Duration // 0 to flush after every value, otherwise every interval, for large exports
+	values        func(ctx context.Context) iter.Seq2[interface{}, errors.Error]
+}
+
+// NDJSON streams the values of seq until it ends, yields an error, or the client disconnects
+func NDJSON[T any](seq iter.Seq2[T, errors.Error]) *NDJSONStream {
+	return &NDJSONStream{
+		values: func(ctx context.Context) iter.Seq2[interface{}, errors.Error] {
+			return func(yield func(interface{}, errors.Error) bool) {
+				for value, err := range seq {
+					if !yield(value, err) {
+						return
+					}
+				}
+			}
+		},
+	}
+}
+
+// NDJSONChannel streams values until the channel is closed, an error arrives on errs, or the client disconnects.
+// errs can be nil. The producer has to close values once it's done, even when the stream ended early: what it sends
+// after that is received and dropped, so it never blocks, but it should still stop when the request's context is done
+// rather than produce values nobody reads.
+func NDJSONChannel[T any](values <-chan T, errs <-chan errors.Error) *NDJSONStream {
+	return &NDJSONStream{
+		values: func(ctx context.Context) iter.Seq2[interface{}, errors.Error] {
+			return func(yield func(interface{}, errors.Error) bool) {
+				for {
+					select {
+					case value, open := <-values:
+						if !open {
+							return
+						}
+						if !yield(value, nil) {
+							go drain(values, errs)
+							return
+						}
+					case err, open := <-errs:
+						if !open {
+							errs = nil
+							continue
+						}
+						yield(nil, err)
+						go drain(values, errs)
+						return
+					case <-ctx.Done():
+						go drain(values, errs)
+						return
+					}
+				}
+			}
+		},
+	}
+}
+
+// drain receives from the channels of a stream that has ended until values is closed, so the producer isn't left
+// blocked on a send
+func drain[T any](values <-chan T, errs <-chan errors.Error) {
+	for {
+		select {
+		case _, open := <-values:
+			if !open {
+				return
+			}
+		case _, open := <-errs:
+			if !open {
+				errs = nil
+			}
+		}
+	}
+}
+
+type ndjsonError struct {
+	Error Problem `json:"error"`
+}
+
+func (s *NDJSONStream) serve(ctx context.Context, w http.ResponseWriter, r *http.Request) errors.Error {
+	controller := startStream(w, "application/x-ndjson")
+
+	// the values and the flush timer both write, so everything written goes through the mutex
+	var mutex sync.Mutex
+	var flushErr errors.Error
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+
+	flush := func() errors.Error {
+		if flushErr != nil {
+			return flushErr
+		}
+
+		err := buffered.Flush()
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			flushErr = writeStreamErr(ctx, err)
+		}
+
+		return flushErr
+	}
+
+	// flushing on a timer rather than when the next value arrives, so values don't sit in the buffer while the source
+	// is slow to produce more
+	if s.FlushInterval > 0 {
+		stopFlushing := make(chan struct{})
+		var flusher sync.WaitGroup
+		defer flusher.Wait()
+		defer close(stopFlushing)
+
+		flusher.Add(1)
+		go func() {
+			defer flusher.Done()
+
+			ticker := time.NewTicker(s.FlushInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					mutex.Lock()
+					if buffered.Buffered() > 0 {
+						_ = flush()
+					}
+					mutex.Unlock()
+				case <-stopFlushing:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var streamErr errors.Error
+	for value, err := range s.values(ctx) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			streamErr = err
+			break
+		}
+
+		mutex.Lock()
+		if encodeErr := encoder.Encode(value); encodeErr != nil {
+			mutex.Unlock()
+			streamErr = errors.Wrap(encodeErr, "Error encoding streamed value")
+			break
+		}
+
+		var writeErr errors.Error
+		if s.FlushInterval <= 0 {
+			writeErr = flush()
+		} else {
+			writeErr = flushErr
+		}
+		mutex.Unlock()
+
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+
+	if ctx.Err() != nil {
+		return writeStreamErr(ctx, ctx.Err())
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if streamErr != nil {
+		_ = encoder.Encode(ndjsonError{NewProblem(streamErr, AcceptedLanguages(r)...)})
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return streamErr
+}
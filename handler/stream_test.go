@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	disconnected := make(chan struct{})
+
+	router := NewRouter()
+	router.Get("/events", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		stream := SSE(func(ctx context.Context, events *EventWriter) errors.Error {
+			if err := events.Send(Event{ID: "6", Name: "resumed", Data: events.LastEventID()}); err != nil {
+				return err
+			}
+			if err := events.Send(Event{ID: "7", Data: map[string]int{"count": 7}}); err != nil {
+				return err
+			}
+			if err := events.Send(Event{Data: "two\nlines"}); err != nil {
+				return err
+			}
+
+			return errors.NewInput("Ran out of events")
+		})
+		stream.Retry = 2 * time.Second
+		return stream, nil
+	})
+	router.Get("/forever", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		stream := SSE(func(ctx context.Context, events *EventWriter) errors.Error {
+			<-ctx.Done()
+			close(disconnected)
+			return nil
+		})
+		stream.Heartbeat = 10 * time.Millisecond
+		return stream, nil
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	t.Run("Sends events, resume ID and errors in-band", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		req.Header.Set("Last-Event-ID", "5")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "retry: 2000\n\n"+
+			"id: 6\nevent: resumed\ndata: 5\n\n"+
+			"id: 7\ndata: {\"count\":7}\n\n"+
+			"data: two\ndata: lines\n\n"+
+			"event: error\ndata: {\"title\":\"Ran out of events\",\"status\":400}\n\n", string(body))
+	})
+
+	t.Run("Heartbeats and disconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/forever", nil)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, ": heartbeat\n", line)
+
+		cancel()
+		select {
+		case <-disconnected:
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream didn't notice the client disconnecting")
+		}
+	})
+}
+
+func TestNDJSON(t *testing.T) {
+	router := NewRouter()
+	router.Get("/seq", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		var seq iter.Seq2[int, errors.Error] = func(yield func(int, errors.Error) bool) {
+			for i := 1; i <= 3; i++ {
+				if !yield(i, nil) {
+					return
+				}
+			}
+			yield(0, errors.NewInput("Export failed"))
+		}
+
+		return NDJSON(seq), nil
+	})
+	router.Get("/channel", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		values := make(chan map[string]string)
+		go func() {
+			defer close(values)
+			values <- map[string]string{"a": "1"}
+			values <- map[string]string{"b": "2"}
+		}()
+
+		stream := NDJSONChannel(values, nil)
+		stream.FlushInterval = time.Second
+		return stream, nil
+	})
+
+	t.Run("Iterator with in-band error", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/seq")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "1\n2\n3\n{\"error\":{\"title\":\"Export failed\",\"status\":400}}\n", recorder.Body.String())
+	})
+
+	t.Run("Channel", func(t *testing.T) {
+		recorder := serve(router, http.MethodGet, "/channel")
+		lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+		assert.Equal(t, []string{`{"a":"1"}`, `{"b":"2"}`}, lines)
+		assert.True(t, recorder.Flushed)
+	})
+
+	t.Run("Flushes while the source is idle", func(t *testing.T) {
+		values := make(chan int)
+		idleRouter := NewRouter()
+		idleRouter.Get("/idle", func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+			stream := NDJSONChannel(values, nil)
+			stream.FlushInterval = 10 * time.Millisecond
+			return stream, nil
+		})
+
+		server := httptest.NewServer(idleRouter)
+		defer server.Close()
+
+		client := &http.Client{Timeout: 2 * time.Second}
+		resp, err := client.Get(server.URL + "/idle")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		values <- 1
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "1\n", line)
+		close(values)
+	})
+
+	t.Run("Channel is drained after the client goes away", func(t *testing.T) {
+		values := make(chan int)
+		produced := make(chan struct{})
+		go func() {
+			defer close(produced)
+			defer close(values)
+			for i := 0; i < 10; i++ {
+				values <- i
+			}
+		}()
+
+		for range NDJSONChannel(values, nil).values(context.Background()) {
+			break
+		}
+
+		select {
+		case <-produced:
+		case <-time.After(time.Second):
+			t.Fatal("producer is blocked sending values nobody reads")
+		}
+	})
+}
@@ -3,34 +3,122 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"github.com/sjohna/go-server-common/errors"
 	"github.com/sjohna/go-server-common/log"
 	"io"
 	"net/http"
 )
 
+const CodeRequestTooLarge = "request_too_large"
+
+func init() {
+	errors.Register(errors.Definition{
+		Code:        CodeRequestTooLarge,
+		HTTPStatus:  http.StatusRequestEntityTooLarge,
+		Severity:    errors.SeverityInfo,
+		Origin:      errors.OriginInput,
+		Message:     "The request body is larger than {limit} bytes",
+		Description: "The request body is over the size limit of the endpoint.",
+	})
+}
+
+type DecodeOptions struct {
+	MaxBytes              int64 // 0 for no limit
+	DisallowUnknownFields bool  // reject fields the destination doesn't have
+	DisallowTrailingData  bool  // reject anything but whitespace after the JSON value
+	UseNumber             bool  // decode numbers into interface{} as json.Number rather than float64
+}
+
+var decodeOptions = DecodeOptions{
+	MaxBytes:              1 << 20,
+	DisallowUnknownFields: false,
+	DisallowTrailingData:  true,
+	UseNumber:             false,
+}
+
+// SetDecodeOptions sets the options of UnmarshalRequestBody. Handler also limits every request body to their MaxBytes.
+func SetDecodeOptions(options DecodeOptions) {
+	decodeOptions = options
+}
+
+// UnmarshalRequestBody decodes a request body with the options set by SetDecodeOptions
 func UnmarshalRequestBody(ctx context.Context, r *http.Request, value interface{}) errors.Error {
-	body, err := io.ReadAll(r.Body)
+	return DecodeRequestBody(ctx, r, value, decodeOptions)
+}
+
+// DecodeRequestBody decodes a request body with the decoder registered for its Content-Type, or as JSON if it has none.
+// JSON is decoded as it's read, so the body is never buffered whole.
+func DecodeRequestBody(ctx context.Context, r *http.Request, value interface{}, options DecodeOptions) errors.Error {
 	defer func() {
 		err := r.Body.Close()
 		if err != nil {
 			myErr := errors.WrapInputError(err, "Error closing request body")
-			log.Ctx(ctx).WithError(myErr).Error("UnmarshalRequestBody: Failed to close request body")
+			log.Ctx(ctx).WithError(myErr).Error("DecodeRequestBody: Failed to close request body")
 		}
 	}()
+
+	decoder, err := requestDecoder(r)
 	if err != nil {
-		return errors.Wrap(err, "Failed to read request body")
+		return err
 	}
 
-	// Unmarshal
-	err = json.Unmarshal(body, value)
-	if err != nil {
-		return errors.WrapInputError(err, "Failed to unmarshal request body")
+	body := r.Body
+	if options.MaxBytes > 0 {
+		if r.ContentLength > options.MaxBytes {
+			return requestTooLarge(nil, options.MaxBytes)
+		}
+
+		body = http.MaxBytesReader(nil, r.Body, options.MaxBytes)
+	}
+
+	decodeErr := decoder(body, value, options)
+	if decodeErr != nil {
+		return decodeError(decodeErr)
 	}
 
 	return nil
 }
 
+var errTrailingData = stderrors.New("unexpected data after value")
+
+func decodeError(err error) errors.Error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case stderrors.As(err, &maxBytesErr):
+		// Handler's limit can be hit before the one in options
+		return requestTooLarge(err, maxBytesErr.Limit)
+	case err == io.EOF:
+		return errors.WrapInputError(err, "Request body is empty")
+	case err == errTrailingData:
+		return errors.WrapInputError(err, "Unexpected data after value in request body")
+	case stderrors.As(err, &typeErr):
+		return errors.WrapInputError(err, "Failed to unmarshal request body").
+			WithPublic("field", typeErr.Field).
+			WithPublic("reason", "expected "+typeErr.Type.String()+", got "+typeErr.Value)
+	case stderrors.As(err, &syntaxErr):
+		return errors.WrapInputError(err, "Request body is not valid JSON").
+			WithPublic("offset", syntaxErr.Offset)
+	case stderrors.Is(err, io.ErrUnexpectedEOF):
+		return errors.WrapInputError(err, "Request body is not valid JSON")
+	}
+
+	// unknown fields and the like only come as plain errors with a message
+	return errors.WrapInputError(err, "Failed to unmarshal request body").
+		WithPublic("reason", err.Error())
+}
+
+func requestTooLarge(err error, maxBytes int64) errors.Error {
+	if err == nil {
+		return errors.FromCode(CodeRequestTooLarge, errors.Params{"limit": maxBytes})
+	}
+
+	return errors.WrapCode(err, CodeRequestTooLarge, errors.Params{"limit": maxBytes})
+}
+
 func RespondJSON(ctx context.Context, w http.ResponseWriter, value interface{}) errors.Error {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type decodeTarget struct {
+	Name  string      `json:"name"`
+	Count int         `json:"count"`
+	Extra interface{} `json:"extra"`
+}
+
+func decode(body string, options DecodeOptions) (decodeTarget, errors.Error) {
+	var target decodeTarget
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	err := DecodeRequestBody(context.Background(), r, &target, options)
+	return target, err
+}
+
+func TestDecodeRequestBody(t *testing.T) {
+	t.Run("Decodes with defaults", func(t *testing.T) {
+		target, err := decode(`{"name":"a","count":2,"unknown":true}`+"\n", decodeOptions)
+		require.Nil(t, err)
+		assert.Equal(t, decodeTarget{Name: "a", Count: 2}, target)
+	})
+
+	t.Run("Body over the limit is 413", func(t *testing.T) {
+		options := DecodeOptions{MaxBytes: 16}
+
+		_, err := decode(`{"name":"`+strings.Repeat("a", 100)+`"}`, options)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, StatusCode(err))
+		assert.Equal(t, "The request body is larger than 16 bytes", err.Error())
+
+		// without a Content-Length, the limit is hit while reading
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`"}`))
+		r.ContentLength = -1
+		err = DecodeRequestBody(context.Background(), r, &decodeTarget{}, options)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, StatusCode(err))
+	})
+
+	t.Run("Unknown fields", func(t *testing.T) {
+		_, err := decode(`{"name":"a","unknown":true}`, DecodeOptions{DisallowUnknownFields: true})
+		require.NotNil(t, err)
+		assert.False(t, err.Internal())
+
+		appErr, _ := errors.AsApplicationError(err)
+		assert.Contains(t, appErr.PublicDetails()["reason"], "unknown")
+	})
+
+	t.Run("Trailing data", func(t *testing.T) {
+		_, err := decode(`{"name":"a"} {"name":"b"}`, DecodeOptions{DisallowTrailingData: true})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, StatusCode(err))
+
+		_, err = decode(`{"name":"a"} {"name":"b"}`, DecodeOptions{})
+		assert.Nil(t, err)
+	})
+
+	t.Run("UseNumber", func(t *testing.T) {
+		target, err := decode(`{"extra":12345678901234567890}`, DecodeOptions{UseNumber: true})
+		require.Nil(t, err)
+		assert.Equal(t, json.Number("12345678901234567890"), target.Extra)
+	})
+
+	t.Run("Bad input", func(t *testing.T) {
+		_, err := decode(``, decodeOptions)
+		assert.Equal(t, "Request body is empty", err.Error())
+
+		_, err = decode(`{"name":`, decodeOptions)
+		assert.Equal(t, "Request body is not valid JSON", err.Error())
+
+		_, err = decode(`{"count":"many"}`, decodeOptions)
+		appErr, _ := errors.AsApplicationError(err)
+		assert.Equal(t, "count", appErr.PublicDetails()["field"])
+		assert.Equal(t, "expected int, got string", appErr.PublicDetails()["reason"])
+	})
+}
+
+func TestOversizedBodyClosesConnection(t *testing.T) {
+	defaults := decodeOptions
+	SetDecodeOptions(DecodeOptions{MaxBytes: 16})
+	defer SetDecodeOptions(defaults)
+
+	server := httptest.NewServer(http.HandlerFunc(Handler(func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		// a larger limit here doesn't lift Handler's
+		return nil, DecodeRequestBody(ctx, r, &decodeTarget{}, DecodeOptions{MaxBytes: 1 << 20})
+	})))
+	defer server.Close()
+
+	// an unknown length, so the limit is only hit while reading
+	body := io.MultiReader(strings.NewReader(`{"name":"`), strings.NewReader(strings.Repeat("a", 1<<16)+`"}`))
+	resp, err := http.Post(server.URL, "application/json", body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	problem, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(problem), "larger than 16 bytes")
+	assert.True(t, resp.Close, "the server closes the connection rather than reading the rest of the body")
+}
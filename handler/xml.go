@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/xml"
+	stderrors "errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// RegisterXML makes responses available as application/xml and text/xml, and accepts request bodies of those types.
+// It isn't registered by default, since browsers ask for XML over anything but HTML, so once it is, browsers get XML
+// from every endpoint.
+func RegisterXML() {
+	RegisterEncoder("application/xml", "application/xml; charset=utf-8", encodeXML)
+	RegisterEncoder("text/xml", "text/xml; charset=utf-8", encodeXML)
+
+	RegisterDecoder("application/xml", decodeXML)
+	RegisterDecoder("text/xml", decodeXML)
+}
+
+// xmlListElement wraps slices, which would otherwise be written as several root elements
+const xmlListElement = "items"
+
+// encodeXML uses encoding/xml both ways, so values need xml tags like they would anywhere else, and what a client gets
+// it can send back. Slices are written as the elements of an items element. Values encoding/xml can't represent, like
+// maps, are left to the next acceptable format.
+func encodeXML(w io.Writer, value interface{}, params map[string]string) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if _, pretty := params["pretty"]; pretty {
+		encoder.Indent("", "  ")
+	}
+
+	err := writeXML(encoder, value)
+	if err == nil {
+		err = encoder.Close()
+	}
+
+	var unsupported *xml.UnsupportedTypeError
+	if stderrors.As(err, &unsupported) {
+		return ErrUnencodable
+	}
+
+	return err
+}
+
+func writeXML(encoder *xml.Encoder, value interface{}) error {
+	list, isList := xmlList(reflect.ValueOf(value))
+	if !isList {
+		return encoder.Encode(value)
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: xmlListElement}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		if err := encoder.Encode(list.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlList returns the slice or array value is, or points to. Byte slices are text rather than lists.
+func xmlList(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) || value.Type().Elem().Kind() == reflect.Uint8 {
+		return value, false
+	}
+
+	return value, true
+}
+
+// decodeXML reads what encodeXML writes. DisallowUnknownFields and UseNumber don't apply.
+func decodeXML(r io.Reader, value interface{}, options DecodeOptions) error {
+	decoder := xml.NewDecoder(r)
+
+	var err error
+	if list, isList := xmlList(reflect.ValueOf(value)); isList && list.Kind() == reflect.Slice && list.CanSet() {
+		err = readXMLList(decoder, list)
+	} else {
+		err = decoder.Decode(value)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !options.DisallowTrailingData {
+		return nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.Comment, xml.ProcInst:
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) != "" {
+				return errTrailingData
+			}
+		default:
+			return errTrailingData
+		}
+	}
+}
+
+// readXMLList decodes each child of the root element into an element of list
+func readXMLList(decoder *xml.Decoder, list reflect.Value) error {
+	if _, err := nextXMLStart(decoder); err != nil {
+		return err
+	}
+
+	elements := reflect.MakeSlice(list.Type(), 0, 0)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := reflect.New(list.Type().Elem())
+			if err := decoder.DecodeElement(element.Interface(), &t); err != nil {
+				return err
+			}
+			elements = reflect.Append(elements, element.Elem())
+		case xml.EndElement:
+			list.Set(elements)
+			return nil
+		}
+	}
+}
+
+func nextXMLStart(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		if start, isStart := token.(xml.StartElement); isStart {
+			return start, nil
+		}
+	}
+}
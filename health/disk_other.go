@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package health
+
+import "github.com/sjohna/go-server-common/errors"
+
+func freeDiskSpace(path string) (uint64, errors.Error) {
+	return 0, errors.New("Disk space check not supported on this platform")
+}
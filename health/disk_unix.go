@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+package health
+
+import (
+	"github.com/sjohna/go-server-common/errors"
+	"syscall"
+)
+
+func freeDiskSpace(path string) (uint64, errors.Error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to stat filesystem of "+path)
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,256 @@
+package health
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/handler"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const CodeNotReady = "not_ready"
+
+func init() {
+	errors.Register(errors.Definition{
+		Code:        CodeNotReady,
+		HTTPStatus:  503,
+		Severity:    errors.SeverityWarning,
+		Origin:      errors.OriginApplication,
+		Message:     "Service is not ready",
+		Description: "A critical health check is failing, or the service is shutting down.",
+	})
+}
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusShutdown = "shutting-down"
+)
+
+type Check struct {
+	Name     string
+	Timeout  time.Duration // the Checker's CheckTimeout if 0
+	Critical bool          // only failing critical checks make the service not ready, others are just reported
+	Func     func(ctx context.Context) errors.Error
+}
+
+type Result struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs registered checks for the readiness endpoint. Results are cached for CacheTTL so a busy load balancer
+// doesn't turn into a busy database.
+type Checker struct {
+	CacheTTL     time.Duration
+	CheckTimeout time.Duration // for checks without a Timeout of their own, so one hanging check can't hold up the report
+
+	mutex        sync.Mutex
+	checks       []Check
+	cached       *Report
+	cachedAt     time.Time
+	running      chan struct{} // closed when the checks being run have finished
+	shuttingDown atomic.Bool
+}
+
+const defaultCheckTimeout = 5 * time.Second
+
+func NewChecker() *Checker {
+	return &Checker{
+		CacheTTL:     time.Second,
+		CheckTimeout: defaultCheckTimeout,
+	}
+}
+
+func (c *Checker) Register(check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.checks = append(c.checks, check)
+	c.cached = nil
+}
+
+// SetShuttingDown makes readiness fail from now on, so load balancers stop sending traffic while requests drain
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// WatchDraining calls SetShuttingDown once draining is closed, e.g. with app.App.Draining()
+func (c *Checker) WatchDraining(draining <-chan struct{}) {
+	go func() {
+		<-draining
+		c.SetShuttingDown()
+	}()
+}
+
+// Check runs every check, or returns the cached report if it's recent enough. Checks run outside the lock, and
+// requests arriving while they run wait for that run instead of starting another.
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShutdown}
+	}
+
+	c.mutex.Lock()
+	if c.cached != nil && time.Since(c.cachedAt) < c.CacheTTL {
+		report := *c.cached
+		c.mutex.Unlock()
+		return report
+	}
+
+	if running := c.running; running != nil {
+		c.mutex.Unlock()
+
+		select {
+		case <-running:
+			return c.Check(ctx)
+		case <-ctx.Done():
+			return Report{Status: StatusFailing}
+		}
+	}
+
+	running := make(chan struct{})
+	c.running = running
+	checks := append([]Check(nil), c.checks...)
+	c.mutex.Unlock()
+
+	// the report is shared between requests, so one client going away shouldn't fail it for everybody
+	report := c.runChecks(context.WithoutCancel(ctx), checks)
+
+	c.mutex.Lock()
+	c.cached = &report
+	c.cachedAt = time.Now()
+	c.running = nil
+	c.mutex.Unlock()
+	close(running)
+
+	return report
+}
+
+func (c *Checker) runChecks(ctx context.Context, checks []Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		if check.Timeout <= 0 {
+			check.Timeout = c.CheckTimeout
+		}
+		if check.Timeout <= 0 {
+			check.Timeout = defaultCheckTimeout
+		}
+
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if check.Critical && results[i].Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+
+	return report
+}
+
+// Liveness only says the process is up and serving requests, so it never runs checks
+func (c *Checker) Liveness() handler.HandlerFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		return Report{Status: StatusOK}, nil
+	}
+}
+
+func (c *Checker) Readiness() handler.HandlerFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, errors.Error) {
+		report := c.Check(ctx)
+		if report.Status != StatusOK {
+			return nil, errors.FromCode(CodeNotReady, nil).
+				WithPublic("status", report.Status).
+				WithPublic("checks", report.Checks)
+		}
+
+		return report, nil
+	}
+}
+
+func runCheck(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan errors.Error, 1)
+
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- errors.Recovered(recovered)
+			}
+		}()
+
+		done <- check.Func(ctx)
+	}()
+
+	var err errors.Error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "Health check "+check.Name+" timed out")
+	}
+
+	result := Result{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func DBCheck(db *sqlx.DB) func(ctx context.Context) errors.Error {
+	return func(ctx context.Context) errors.Error {
+		err := db.PingContext(ctx)
+		if err != nil {
+			return errors.WrapDBError(err, "Failed to ping database")
+		}
+		return nil
+	}
+}
+
+func DiskSpaceCheck(path string, minFreeBytes uint64) func(ctx context.Context) errors.Error {
+	return func(ctx context.Context) errors.Error {
+		free, err := freeDiskSpace(path)
+		if err != nil {
+			return err
+		}
+
+		if free < minFreeBytes {
+			return errors.New("Low disk space").
+				With("path", path).
+				With("freeBytes", free).
+				With("minFreeBytes", minFreeBytes)
+		}
+
+		return nil
+	}
+}
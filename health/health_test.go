@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/handler"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	ctx := context.Background()
+	cacheFailing := false
+
+	checker := NewChecker()
+	checker.CacheTTL = time.Hour
+	checker.Register(Check{
+		Name:     "cache",
+		Critical: false,
+		Func: func(ctx context.Context) errors.Error {
+			if cacheFailing {
+				return errors.New("cache down")
+			}
+			return nil
+		},
+	})
+	checker.Register(Check{
+		Name:     "slow",
+		Timeout:  10 * time.Millisecond,
+		Critical: true,
+		Func: func(ctx context.Context) errors.Error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	t.Run("Critical check timing out fails readiness", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		r.Header.Set("Accept", "application/json")
+		handler.Handler(checker.Readiness())(recorder, r)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+		var problem handler.Problem
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		assert.Equal(t, CodeNotReady, problem.Code)
+		assert.Equal(t, StatusFailing, problem.Details["status"])
+	})
+
+	t.Run("Non-critical failures reported but ready", func(t *testing.T) {
+		checker.mutex.Lock()
+		checker.checks = checker.checks[:1]
+		checker.cached = nil
+		checker.mutex.Unlock()
+		cacheFailing = true
+
+		report := checker.Check(ctx)
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Equal(t, StatusFailing, report.Checks["cache"].Status)
+		assert.Equal(t, "cache down", report.Checks["cache"].Error)
+	})
+
+	t.Run("Results cached", func(t *testing.T) {
+		cacheFailing = false
+		assert.Equal(t, StatusFailing, checker.Check(ctx).Checks["cache"].Status)
+	})
+
+	t.Run("Fails once shutting down", func(t *testing.T) {
+		draining := make(chan struct{})
+		checker.WatchDraining(draining)
+		close(draining)
+
+		assert.Eventually(t, func() bool {
+			return checker.Check(ctx).Status == StatusShutdown
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Disk space", func(t *testing.T) {
+		assert.Nil(t, DiskSpaceCheck(t.TempDir(), 1)(ctx))
+		assert.NotNil(t, DiskSpaceCheck(t.TempDir(), 1<<62)(ctx))
+	})
+}
+
+func TestHangingCheck(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	checker := NewChecker()
+	checker.CheckTimeout = 20 * time.Millisecond
+	checker.Register(Check{
+		Name:     "hanging",
+		Critical: true,
+		Func: func(ctx context.Context) errors.Error {
+			<-hang // ignores the context
+			return nil
+		},
+	})
+
+	reports := make(chan Report, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			reports <- checker.Check(context.Background())
+		}()
+	}
+
+	// the lock isn't held while checks run
+	registered := make(chan struct{})
+	go func() {
+		checker.Register(Check{Name: "other", Func: func(ctx context.Context) errors.Error { return nil }})
+		close(registered)
+	}()
+
+	select {
+	case <-registered:
+	case <-time.After(time.Second):
+		t.Fatal("Register blocked while checks were running")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case report := <-reports:
+			assert.Equal(t, StatusFailing, report.Status)
+			assert.Contains(t, report.Checks["hanging"].Error, "timed out")
+		case <-time.After(time.Second):
+			t.Fatal("hanging check without a timeout blocked the report")
+		}
+	}
+}
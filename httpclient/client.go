@@ -0,0 +1,284 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/breaker"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/sjohna/go-server-common/retry"
+	"github.com/sjohna/go-server-common/tracing"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls another API. Every failure comes back as an ApplicationError with OriginThirdParty, including non-2xx
+// responses, which carry the status and the start of the response body as details.
+type Client struct {
+	Name         string // name of the dependency, for logs and errors
+	BaseURL      string
+	HTTPClient   *http.Client
+	Timeout      time.Duration    // per attempt, 0 for no timeout beyond the context's
+	RetryPolicy  *retry.Policy    // nil to never retry
+	Breaker      *breaker.Breaker // nil to not use a circuit breaker
+	MaxErrorBody int              // bytes of an unsuccessful response's body to keep in the error
+
+	// POST and PATCH can repeat their effect if an attempt got through, so they're only retried with this set or an
+	// Idempotency-Key header
+	RetryNonIdempotent bool
+}
+
+func New(name string, baseURL string) *Client {
+	return &Client{
+		Name:         name,
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:   http.DefaultClient,
+		Timeout:      30 * time.Second,
+		RetryPolicy:  nil,
+		Breaker:      nil,
+		MaxErrorBody: 1024,
+
+		RetryNonIdempotent: false,
+	}
+}
+
+// Do sends the request, retrying according to RetryPolicy if it's safe to repeat and its body can be replayed. Only 2xx
+// responses are returned, and the caller must close their body.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, errors.Error) {
+	if c.RetryPolicy == nil || !c.retryable(req) {
+		return c.do(ctx, req)
+	}
+
+	var resp *http.Response
+	attempt := 0
+	err := retry.Do(ctx, *c.RetryPolicy, func() errors.Error {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return errors.Wrap(err, "Failed to replay request body")
+			}
+
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		attempt++
+
+		var err errors.Error
+		resp, err = c.do(ctx, attemptReq)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return c.RetryNonIdempotent || req.Header.Get("Idempotency-Key") != ""
+}
+
+// DoJSON sends body, if not nil, as JSON and decodes a JSON response into dest, if not nil
+func (c *Client) DoJSON(ctx context.Context, method string, path string, body interface{}, dest interface{}) errors.Error {
+	req, err := c.NewJSONRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer c.closeBody(ctx, resp)
+
+	if dest == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	decodeErr := json.NewDecoder(resp.Body).Decode(dest)
+	if decodeErr != nil {
+		return errors.WrapThirdParty(decodeErr, "Failed to decode JSON response from "+c.Name).
+			With("dependency", c.Name).
+			With("url", safeURL(req.URL))
+	}
+
+	return nil
+}
+
+func (c *Client) GetJSON(ctx context.Context, path string, dest interface{}) errors.Error {
+	return c.DoJSON(ctx, http.MethodGet, path, nil, dest)
+}
+
+func (c *Client) PostJSON(ctx context.Context, path string, body interface{}, dest interface{}) errors.Error {
+	return c.DoJSON(ctx, http.MethodPost, path, body, dest)
+}
+
+// Decode is DoJSON for when the response type is known up front
+func Decode[T any](ctx context.Context, c *Client, method string, path string, body interface{}) (T, errors.Error) {
+	var result T
+	err := c.DoJSON(ctx, method, path, body, &result)
+	return result, err
+}
+
+func (c *Client) NewJSONRequest(ctx context.Context, method string, path string, body interface{}) (*http.Request, errors.Error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to marshal JSON request body for "+c.Name)
+		}
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bodyReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request for "+c.Name)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, errors.Error) {
+	if c.Breaker == nil {
+		return c.send(ctx, req)
+	}
+
+	var resp *http.Response
+	err := c.Breaker.Execute(ctx, func() errors.Error {
+		var err errors.Error
+		resp, err = c.send(ctx, req)
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, errors.Error) {
+	ctx, span := tracing.Start(ctx, "HTTP "+req.Method+" "+c.Name, tracing.KindClient)
+	span.SetAttribute("peer.service", c.Name)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", safeURL(req.URL))
+	defer span.End()
+
+	logger := log.Ctx(ctx).WithFields(log.Fields{
+		"dependency": c.Name,
+		"method":     req.Method,
+		"url":        safeURL(req.URL),
+	})
+
+	reqCtx := req.Context()
+	cancel := context.CancelFunc(func() {})
+	if c.Timeout > 0 {
+		reqCtx, cancel = context.WithTimeout(reqCtx, c.Timeout)
+	}
+
+	// cloned rather than shallow copied, so propagation headers don't leak into the caller's request
+	req = req.Clone(reqCtx)
+	tracing.Inject(span.SpanContext(), req.Header)
+	if requestID := log.RequestID(ctx); requestID != "" && req.Header.Get(log.RequestIDHeader) == "" {
+		req.Header.Set(log.RequestIDHeader, requestID)
+	}
+
+	logger.Debug("Sending request")
+	start := time.Now()
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		wrapped := errors.WrapThirdParty(err, "Request to "+c.Name+" failed").
+			With("dependency", c.Name).
+			With("url", safeURL(req.URL))
+		span.SetError(wrapped)
+		return nil, wrapped
+	}
+
+	span.SetAttribute("http.status_code", resp.StatusCode)
+
+	logger.WithFields(log.Fields{
+		"status":   resp.StatusCode,
+		"duration": time.Since(start).String(),
+	}).Debug("Received response")
+
+	// the timeout has to outlive this function, since the caller still has to read the body
+	resp.Body = &cancelOnClose{resp.Body, cancel}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body := c.readErrorBody(ctx, resp)
+
+		statusErr := errors.NewThirdPartyStatus(resp.StatusCode, c.Name+" responded with "+resp.Status).
+			With("dependency", c.Name).
+			With("url", safeURL(req.URL)).
+			With("status", resp.StatusCode).
+			With("responseBody", body)
+		span.SetError(statusErr)
+		return nil, statusErr
+	}
+
+	return resp, nil
+}
+
+func (c *Client) readErrorBody(ctx context.Context, resp *http.Response) string {
+	defer c.closeBody(ctx, resp)
+
+	if c.MaxErrorBody <= 0 {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(c.MaxErrorBody)))
+	if err != nil {
+		log.Ctx(ctx).WithError(errors.WrapThirdParty(err, "Error reading response body")).Warn("Failed to read body of unsuccessful response")
+	}
+
+	return string(body)
+}
+
+func (c *Client) closeBody(ctx context.Context, resp *http.Response) {
+	// drain whatever is left so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	err := resp.Body.Close()
+	if err != nil {
+		log.Ctx(ctx).WithError(errors.WrapThirdParty(err, "Error closing response body")).Warn("Failed to close response body")
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// safeURL is the URL for spans, logs and errors. Query strings and user info often carry tokens, so they're left out.
+func safeURL(u *url.URL) string {
+	stripped := *u
+	stripped.User = nil
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return stripped.String()
+}
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/sjohna/go-server-common/retry"
+	"github.com/sjohna/go-server-common/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type widget struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestClient(t *testing.T) {
+	var failuresLeft int32
+	var lastRequestID atomic.Value
+	var lastTraceparent atomic.Value
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/widget", func(w http.ResponseWriter, r *http.Request) {
+		lastRequestID.Store(r.Header.Get(log.RequestIDHeader))
+		lastTraceparent.Store(r.Header.Get(tracing.TraceparentHeader))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"name":"sprocket"}`))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failuresLeft, -1) >= 0 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+
+		var body widget
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("x", 100), http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := New("widgets", server.URL)
+	client.MaxErrorBody = 10
+
+	ctx := log.WithRequestID(context.Background(), "request-1")
+	ctx, span := tracing.Start(ctx, "test", tracing.KindInternal)
+
+	t.Run("Decodes JSON and propagates request ID and trace", func(t *testing.T) {
+		result, err := Decode[widget](ctx, client, http.MethodGet, "/widget", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, widget{1, "sprocket"}, result)
+		assert.Equal(t, "request-1", lastRequestID.Load())
+
+		sc, valid := tracing.ParseTraceparent(lastTraceparent.Load().(string))
+		assert.True(t, valid)
+		assert.Equal(t, span.SpanContext().TraceID, sc.TraceID)
+		assert.NotEqual(t, span.SpanContext().SpanID, sc.SpanID)
+	})
+
+	t.Run("Non-2xx becomes third-party error with truncated body", func(t *testing.T) {
+		err := client.GetJSON(ctx, "/broken", nil)
+		appErr, isAppErr := errors.AsApplicationError(err)
+		assert.True(t, isAppErr)
+		assert.Equal(t, errors.OriginThirdParty, int(appErr.Origin))
+		assert.False(t, appErr.Retryable())
+		assert.Equal(t, 500, appErr.Details()["status"])
+		assert.Equal(t, "xxxxxxxxxx", appErr.Details()["responseBody"])
+	})
+
+	t.Run("Query strings are left out of URLs", func(t *testing.T) {
+		err := client.GetJSON(ctx, "/broken?token=secret#part", nil)
+		appErr, _ := errors.AsApplicationError(err)
+		assert.Equal(t, server.URL+"/broken", appErr.Details()["url"])
+	})
+
+	t.Run("Retries 503 with replayed body", func(t *testing.T) {
+		atomic.StoreInt32(&failuresLeft, 2)
+
+		retryingClient := New("widgets", server.URL)
+		retryingClient.RetryPolicy = &retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+		retryingClient.RetryNonIdempotent = true
+
+		var result widget
+		err := retryingClient.PostJSON(ctx, "/echo", widget{2, "gear"}, &result)
+		assert.Nil(t, err)
+		assert.Equal(t, widget{2, "gear"}, result)
+	})
+
+	t.Run("Only retries POST when opted in", func(t *testing.T) {
+		retryingClient := New("widgets", server.URL)
+		retryingClient.RetryPolicy = &retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+
+		atomic.StoreInt32(&failuresLeft, 2)
+		err := retryingClient.PostJSON(ctx, "/echo", widget{2, "gear"}, nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&failuresLeft), "a POST is sent once")
+
+		atomic.StoreInt32(&failuresLeft, 2)
+		err = retryingClient.DoJSON(ctx, http.MethodPut, "/echo", widget{2, "gear"}, nil)
+		assert.Nil(t, err)
+
+		atomic.StoreInt32(&failuresLeft, 2)
+		req, err := retryingClient.NewJSONRequest(ctx, http.MethodPost, "/echo", widget{2, "gear"})
+		require.Nil(t, err)
+		req.Header.Set("Idempotency-Key", "key-1")
+		resp, err := retryingClient.Do(ctx, req)
+		require.Nil(t, err)
+		_ = resp.Body.Close()
+	})
+
+	t.Run("Timeout is classified", func(t *testing.T) {
+		slowClient := New("widgets", server.URL)
+		slowClient.Timeout = 10 * time.Millisecond
+
+		err := slowClient.GetJSON(ctx, "/slow", nil)
+		appErr, isAppErr := errors.AsApplicationError(err)
+		assert.True(t, isAppErr)
+		assert.Equal(t, errors.CodeTimeout, appErr.Code)
+		assert.True(t, appErr.Temporary())
+	})
+}
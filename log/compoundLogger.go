@@ -1,14 +1,28 @@
 package log
 
-import "github.com/sjohna/go-server-common/errors"
+import (
+	"github.com/rs/zerolog"
+	"github.com/sjohna/go-server-common/errors"
+)
 
 type CompoundLogger struct {
-	loggers []Logger
+	loggers   []Logger
+	uncounted bool
 }
 
+// NewCompoundLogger counts each line once, rather than once per logger in loggers
 func NewCompoundLogger(loggers []Logger) CompoundLogger {
+	counted := make([]Logger, len(loggers))
+	for i, logger := range loggers {
+		if counter, isCounter := logger.(lineCounter); isCounter {
+			logger = counter.withoutLineCount()
+		}
+		counted[i] = logger
+	}
+
 	return CompoundLogger{
-		loggers,
+		counted,
+		false,
 	}
 }
 
@@ -17,7 +31,7 @@ func (l CompoundLogger) WithField(key string, value interface{}) Logger {
 	for i, logger := range l.loggers {
 		newLoggers[i] = logger.WithField(key, value)
 	}
-	return NewCompoundLogger(newLoggers)
+	return CompoundLogger{newLoggers, l.uncounted}
 }
 
 func (l CompoundLogger) WithFields(fields map[string]interface{}) Logger {
@@ -25,7 +39,7 @@ func (l CompoundLogger) WithFields(fields map[string]interface{}) Logger {
 	for i, logger := range l.loggers {
 		newLoggers[i] = logger.WithFields(fields)
 	}
-	return NewCompoundLogger(newLoggers)
+	return CompoundLogger{newLoggers, l.uncounted}
 }
 
 func (l CompoundLogger) WithError(err errors.Error) Logger {
@@ -33,88 +47,102 @@ func (l CompoundLogger) WithError(err errors.Error) Logger {
 	for i, logger := range l.loggers {
 		newLoggers[i] = logger.WithError(err)
 	}
-	return NewCompoundLogger(newLoggers)
+	return CompoundLogger{newLoggers, l.uncounted}
 }
 
 func (l CompoundLogger) Trace(msg string) {
+	l.countLine(zerolog.TraceLevel)
 	for _, logger := range l.loggers {
 		logger.Trace(msg)
 	}
 }
 
 func (l CompoundLogger) Tracef(format string, v ...interface{}) {
+	l.countLine(zerolog.TraceLevel)
 	for _, logger := range l.loggers {
 		logger.Tracef(format, v...)
 	}
 }
 
 func (l CompoundLogger) Debug(msg string) {
+	l.countLine(zerolog.DebugLevel)
 	for _, logger := range l.loggers {
 		logger.Debug(msg)
 	}
 }
 
 func (l CompoundLogger) Debugf(format string, v ...interface{}) {
+	l.countLine(zerolog.DebugLevel)
 	for _, logger := range l.loggers {
 		logger.Debugf(format, v...)
 	}
 }
 
 func (l CompoundLogger) Info(msg string) {
+	l.countLine(zerolog.InfoLevel)
 	for _, logger := range l.loggers {
 		logger.Info(msg)
 	}
 }
 
 func (l CompoundLogger) Infof(format string, v ...interface{}) {
+	l.countLine(zerolog.InfoLevel)
 	for _, logger := range l.loggers {
 		logger.Infof(format, v...)
 	}
 }
 
 func (l CompoundLogger) Warn(msg string) {
+	l.countLine(zerolog.WarnLevel)
 	for _, logger := range l.loggers {
 		logger.Warn(msg)
 	}
 }
 
 func (l CompoundLogger) Warnf(format string, v ...interface{}) {
+	l.countLine(zerolog.WarnLevel)
 	for _, logger := range l.loggers {
 		logger.Warnf(format, v...)
 	}
 }
 
 func (l CompoundLogger) Error(msg string) {
+	l.countLine(zerolog.ErrorLevel)
 	for _, logger := range l.loggers {
 		logger.Error(msg)
 	}
 }
 
 func (l CompoundLogger) Errorf(format string, v ...interface{}) {
+	l.countLine(zerolog.ErrorLevel)
 	for _, logger := range l.loggers {
 		logger.Errorf(format, v...)
 	}
 }
 
 func (l CompoundLogger) Panic(msg string) {
+	l.countLine(zerolog.PanicLevel)
 	for _, logger := range l.loggers {
 		logger.Panic(msg)
 	}
 }
 
 func (l CompoundLogger) Panicf(format string, v ...interface{}) {
+	l.countLine(zerolog.PanicLevel)
 	for _, logger := range l.loggers {
 		logger.Panicf(format, v...)
 	}
 }
 
 func (l CompoundLogger) Fatal(msg string) {
+	l.countLine(zerolog.FatalLevel)
 	for _, logger := range l.loggers {
 		logger.Fatal(msg)
 	}
 }
 
 func (l CompoundLogger) Fatalf(format string, v ...interface{}) {
+	l.countLine(zerolog.FatalLevel)
 	for _, logger := range l.loggers {
 		logger.Fatalf(format, v...)
 	}
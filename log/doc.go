@@ -0,0 +1,6 @@
+// Package log is the Logger interface and its zerolog-backed implementations, with a logger per request carried in the
+// context.
+//
+// SetGlobalLoggers, SetErrorLevelRules and SetAlertHook change package-wide settings without synchronization, so call
+// them during startup, before anything logs.
+package log
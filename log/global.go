@@ -2,18 +2,18 @@ package log
 
 import "context"
 
-var General Logger
-var Config Logger
+// these discard everything until SetGlobalLoggers is called
+var General Logger = NewMultiplexLogger(nil)
+var Config Logger = NewMultiplexLogger(nil)
 
-// TODO: init these here
 func SetGlobalLoggers(general, config Logger) {
 	General = general
 	Config = config
 }
 
 func Ctx(ctx context.Context) Logger {
-	if ctx.Value("logger").(Logger) != nil {
-		return ctx.Value("logger").(Logger)
+	if logger, isLogger := ctx.Value("logger").(Logger); isLogger && logger != nil {
+		return logger
 	}
 
 	return General
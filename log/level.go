@@ -0,0 +1,110 @@
+package log
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"slices"
+)
+
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelCritical // logged at zerolog's fatal level with critical=true, without exiting
+)
+
+// ErrorLevelRule maps errors to the level they're logged at. Empty match lists match anything.
+type ErrorLevelRule struct {
+	Origins    []errors.Origin
+	Severities []errors.Severity
+	Codes      []string
+	Level      Level
+}
+
+type AlertHook func(ctx context.Context, err errors.Error, msg string)
+
+var errorLevelRules []ErrorLevelRule
+var alertHook AlertHook
+
+// rules are checked in order and the first match wins. Errors matching no rule are logged according to their severity.
+func SetErrorLevelRules(rules ...ErrorLevelRule) {
+	errorLevelRules = rules
+}
+
+// the hook is called after an error is logged at LevelCritical
+func SetAlertHook(hook AlertHook) {
+	alertHook = hook
+}
+
+func ErrorLevel(err errors.Error) Level {
+	appErr, isAppErr := errors.AsApplicationError(err)
+	if !isAppErr {
+		if err.Warning() {
+			return LevelWarn
+		}
+		return LevelError
+	}
+
+	for _, rule := range errorLevelRules {
+		if rule.matches(appErr) {
+			return rule.Level
+		}
+	}
+
+	switch appErr.Severity {
+	case errors.SeverityDebug:
+		return LevelDebug
+	case errors.SeverityInfo:
+		return LevelInfo
+	case errors.SeverityWarning:
+		return LevelWarn
+	case errors.SeverityCritical:
+		return LevelCritical
+	}
+
+	return LevelError
+}
+
+// LogError logs err through the context logger at the level it maps to
+func LogError(ctx context.Context, err errors.Error, msg string) {
+	logger := Ctx(ctx).WithError(err)
+
+	switch ErrorLevel(err) {
+	case LevelTrace:
+		logger.Trace(msg)
+	case LevelDebug:
+		logger.Debug(msg)
+	case LevelInfo:
+		logger.Info(msg)
+	case LevelWarn:
+		logger.Warn(msg)
+	case LevelError:
+		logger.Error(msg)
+	case LevelCritical:
+		logger.WithField("critical", true).Fatal(msg)
+
+		if alertHook != nil {
+			alertHook(ctx, err, msg)
+		}
+	}
+}
+
+func (rule ErrorLevelRule) matches(err *errors.ApplicationError) bool {
+	if len(rule.Origins) > 0 && !slices.Contains(rule.Origins, err.Origin) {
+		return false
+	}
+
+	if len(rule.Severities) > 0 && !slices.Contains(rule.Severities, err.Severity) {
+		return false
+	}
+
+	if len(rule.Codes) > 0 && !slices.Contains(rule.Codes, err.Code) {
+		return false
+	}
+
+	return true
+}
@@ -2,8 +2,12 @@ package log
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"github.com/rs/zerolog"
+	"github.com/sjohna/go-server-common/errors"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"testing"
 )
 
@@ -162,3 +166,109 @@ func TestCompoundLogger(t *testing.T) {
 		assert.Equal(t, `{"level":"info","key1":"value1","message":"test"}`+"\n", loggedInfo2)
 	})
 }
+
+func TestLineCounts(t *testing.T) {
+	infoLogger := NewMultiplexLogger([]zerolog.Logger{zerolog.New(io.Discard).Level(zerolog.InfoLevel)})
+	debugLogger := NewMultiplexLogger([]zerolog.Logger{zerolog.New(io.Discard).Level(zerolog.DebugLevel)})
+	logger := NewCompoundLogger([]Logger{infoLogger, NewCompoundLogger([]Logger{debugLogger})})
+
+	count := func(level zerolog.Level, log func()) float64 {
+		before := logLinesTotal.With(level.String()).Value()
+		log()
+		return logLinesTotal.With(level.String()).Value() - before
+	}
+
+	assert.Equal(t, 1.0, count(zerolog.InfoLevel, func() { logger.WithField("key", "value").Info("test") }))
+	assert.Equal(t, 1.0, count(zerolog.DebugLevel, func() { logger.Debug("test") }))
+	assert.Equal(t, 0.0, count(zerolog.TraceLevel, func() { logger.Trace("test") }))
+	assert.Equal(t, 1.0, count(zerolog.InfoLevel, func() { infoLogger.Info("test") }))
+}
+
+func TestMultiplexLoggerWithError(t *testing.T) {
+	errors.SetStackTraceOptions(errors.StackTraceOptions{MaxDepth: 0})
+	defer errors.SetStackTraceOptions(errors.StackTraceOptions{MaxDepth: 32, FilterRuntime: true})
+
+	outBuffer := bytes.NewBuffer([]byte{})
+	zLogger := zerolog.New(outBuffer).Level(zerolog.InfoLevel)
+
+	t.Run("Wrapped error chain", func(t *testing.T) {
+		logger := NewMultiplexLogger([]zerolog.Logger{zLogger})
+		err := errors.Wrap(errors.WrapQueryError(fmt.Errorf("inner"), "query failed", "SELECT 1"), "outer")
+		logger.WithError(err).Error("test")
+		logged := outBuffer.String()
+		assert.Equal(t, `{"level":"error","error":{"message":"outer","severity":"error","origin":"application","inner":{"message":"query failed","severity":"error","origin":"third-party","query":"SELECT 1","inner":{"message":"inner"}}},"errorFingerprint":"`+err.Fingerprint()+`","message":"test"}`+"\n", logged)
+	})
+
+	t.Run("Error is marshaled once for all loggers", func(t *testing.T) {
+		first := bytes.NewBuffer([]byte{})
+		second := bytes.NewBuffer([]byte{})
+		logger := NewMultiplexLogger([]zerolog.Logger{zerolog.New(first), zerolog.New(second)})
+
+		err := &countingError{ApplicationError: errors.New("counted")}
+		logger.WithError(err).Error("test")
+
+		assert.Equal(t, 1, err.marshaled)
+		assert.Equal(t, 1, err.fingerprinted)
+		assert.Equal(t, first.String(), second.String())
+		assert.Contains(t, first.String(), `"errorFingerprint":"fp"`)
+	})
+}
+
+type countingError struct {
+	*errors.ApplicationError
+	marshaled     int
+	fingerprinted int
+}
+
+func (e *countingError) MarshalJSON() ([]byte, error) {
+	e.marshaled++
+	return e.ApplicationError.MarshalJSON()
+}
+
+func (e *countingError) Fingerprint() string {
+	e.fingerprinted++
+	return "fp"
+}
+
+func TestErrorLevel(t *testing.T) {
+	defer SetErrorLevelRules()
+
+	notFound := errors.NewInput("not found")
+	notFound.Code = "not_found"
+
+	t.Run("Default from severity", func(t *testing.T) {
+		assert.Equal(t, LevelWarn, ErrorLevel(notFound))
+		assert.Equal(t, LevelError, ErrorLevel(errors.New("test")))
+	})
+
+	t.Run("First matching rule wins", func(t *testing.T) {
+		SetErrorLevelRules(
+			ErrorLevelRule{Codes: []string{"not_found"}, Level: LevelDebug},
+			ErrorLevelRule{Origins: []errors.Origin{errors.OriginInput}, Level: LevelInfo},
+		)
+
+		assert.Equal(t, LevelDebug, ErrorLevel(notFound))
+		assert.Equal(t, LevelInfo, ErrorLevel(errors.NewInput("bad input")))
+		assert.Equal(t, LevelError, ErrorLevel(errors.New("test")))
+	})
+
+	t.Run("Critical errors call alert hook", func(t *testing.T) {
+		defer SetAlertHook(nil)
+
+		var alerted errors.Error
+		SetAlertHook(func(ctx context.Context, err errors.Error, msg string) {
+			alerted = err
+		})
+
+		outBuffer := bytes.NewBuffer([]byte{})
+		ctx := context.WithValue(context.Background(), "logger", NewMultiplexLogger([]zerolog.Logger{zerolog.New(outBuffer).Level(zerolog.FatalLevel)}))
+
+		corrupted := errors.New("data corrupted")
+		corrupted.Severity = errors.SeverityCritical
+		LogError(ctx, corrupted, "test")
+
+		assert.Equal(t, errors.Error(corrupted), alerted)
+		assert.Contains(t, outBuffer.String(), `"level":"fatal"`)
+		assert.Contains(t, outBuffer.String(), `"critical":true`)
+	})
+}
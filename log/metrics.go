@@ -0,0 +1,56 @@
+package log
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/sjohna/go-server-common/metrics"
+)
+
+var logLinesTotal = metrics.NewCounterVec("log_lines_total", "Log lines written, by level.", "level")
+
+// lineCounter is a logger from this package, which a CompoundLogger stops from counting lines itself
+type lineCounter interface {
+	withoutLineCount() Logger
+	enabled(level zerolog.Level) bool
+}
+
+// lines are only counted if at least one of the loggers would write them
+func (l MultiplexLogger) countLine(level zerolog.Level) {
+	if !l.uncounted && l.enabled(level) {
+		logLinesTotal.With(level.String()).Inc()
+	}
+}
+
+func (l MultiplexLogger) enabled(level zerolog.Level) bool {
+	for _, logger := range l.loggers {
+		if logger.GetLevel() <= level {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l MultiplexLogger) withoutLineCount() Logger {
+	return MultiplexLogger{l.loggers, true}
+}
+
+func (l CompoundLogger) countLine(level zerolog.Level) {
+	if !l.uncounted && l.enabled(level) {
+		logLinesTotal.With(level.String()).Inc()
+	}
+}
+
+// loggers from outside this package are assumed to write every line
+func (l CompoundLogger) enabled(level zerolog.Level) bool {
+	for _, logger := range l.loggers {
+		if counter, isCounter := logger.(lineCounter); !isCounter || counter.enabled(level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l CompoundLogger) withoutLineCount() Logger {
+	return CompoundLogger{l.loggers, true}
+}
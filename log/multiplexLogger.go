@@ -1,17 +1,20 @@
 package log
 
 import (
+	"encoding/json"
 	"github.com/rs/zerolog"
 	"github.com/sjohna/go-server-common/errors"
 )
 
 type MultiplexLogger struct {
-	loggers []zerolog.Logger
+	loggers   []zerolog.Logger
+	uncounted bool // lines are counted by the CompoundLogger this is part of
 }
 
 func NewMultiplexLogger(loggers []zerolog.Logger) MultiplexLogger {
 	return MultiplexLogger{
 		loggers,
+		false,
 	}
 }
 
@@ -20,7 +23,7 @@ func (l MultiplexLogger) WithField(key string, value interface{}) Logger {
 	for i, logger := range l.loggers {
 		newLoggers[i] = logger.With().Interface(key, value).Logger()
 	}
-	return NewMultiplexLogger(newLoggers)
+	return MultiplexLogger{newLoggers, l.uncounted}
 }
 
 func (l MultiplexLogger) WithFields(fields map[string]interface{}) Logger {
@@ -28,112 +31,169 @@ func (l MultiplexLogger) WithFields(fields map[string]interface{}) Logger {
 	for i, logger := range l.loggers {
 		newLoggers[i] = logger.With().Fields(fields).Logger()
 	}
-	return NewMultiplexLogger(newLoggers)
+	return MultiplexLogger{newLoggers, l.uncounted}
 }
 
 func (l MultiplexLogger) WithError(err errors.Error) Logger {
+	// marshaled once rather than for every logger
+	fields := []rawField{marshalField(zerolog.ErrorFieldName, err)}
+	if appErr, isAppErr := errors.AsApplicationError(err); isAppErr {
+		if details := appErr.Details(); details != nil {
+			fields = append(fields, marshalField("errorDetails", details))
+		}
+
+		if appFrame, found := appErr.StackTrace.FirstAppFrame(); found {
+			fields = append(fields, marshalField("errorFirstAppFrame", appFrame))
+		}
+	}
+
+	fingerprinter, isFingerprinter := err.(interface{ Fingerprint() string })
+	var fingerprint string
+	if isFingerprinter {
+		fingerprint = fingerprinter.Fingerprint()
+	}
+
 	newLoggers := make([]zerolog.Logger, len(l.loggers))
 	for i, logger := range l.loggers {
-		newLoggers[i] = logger.With().Err(err).Logger()
-
-		if queryErr, isQueryErr := err.(*errors.QueryError); isQueryErr {
-			newLoggers[i] = logger.With().Fields(map[string]interface{}{
-				"origin":     errors.OriginString(queryErr.Origin),
-				"errorStack": queryErr.StackTrace,
-				"innerError": queryErr.Inner,
-				"query":      queryErr.Query,
-				"queryArgs":  queryErr.Args,
-			}).Logger()
-		} else if appErr, isAppErr := err.(*errors.ApplicationError); isAppErr {
-			newLoggers[i] = logger.With().Fields(map[string]interface{}{
-				"origin":     errors.OriginString(appErr.Origin),
-				"errorStack": appErr.StackTrace,
-				"innerError": appErr.Inner,
-			}).Logger()
+		context := logger.With()
+		for _, field := range fields {
+			context = field.add(context)
+		}
+		if isFingerprinter {
+			context = context.Str("errorFingerprint", fingerprint)
+		}
+		newLoggers[i] = context.Logger()
+	}
+	return MultiplexLogger{newLoggers, l.uncounted}
+}
+
+type rawField struct {
+	key   string
+	value interface{}
+	json  []byte
+}
+
+// marshalField encodes value up front, falling back to zerolog's handling of it if it can't be marshaled, e.g. an
+// error without MarshalJSON
+func marshalField(key string, value interface{}) rawField {
+	if _, isError := value.(error); isError {
+		if _, isMarshaler := value.(json.Marshaler); !isMarshaler {
+			return rawField{key, value, nil}
 		}
 	}
-	return NewMultiplexLogger(newLoggers)
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return rawField{key, value, nil}
+	}
+
+	return rawField{key, nil, body}
+}
+
+func (f rawField) add(context zerolog.Context) zerolog.Context {
+	if f.json != nil {
+		return context.RawJSON(f.key, f.json)
+	}
+	if err, isError := f.value.(error); isError {
+		return context.AnErr(f.key, err)
+	}
+
+	return context.Interface(f.key, f.value)
 }
 
 func (l MultiplexLogger) Trace(msg string) {
+	l.countLine(zerolog.TraceLevel)
 	for _, logger := range l.loggers {
 		logger.Trace().Msg(msg)
 	}
 }
 
 func (l MultiplexLogger) Tracef(format string, v ...interface{}) {
+	l.countLine(zerolog.TraceLevel)
 	for _, logger := range l.loggers {
 		logger.Trace().Msgf(format, v...)
 	}
 }
 
 func (l MultiplexLogger) Debug(msg string) {
+	l.countLine(zerolog.DebugLevel)
 	for _, logger := range l.loggers {
 		logger.Debug().Msg(msg)
 	}
 }
 
 func (l MultiplexLogger) Debugf(format string, v ...interface{}) {
+	l.countLine(zerolog.DebugLevel)
 	for _, logger := range l.loggers {
 		logger.Debug().Msgf(format, v...)
 	}
 }
 
 func (l MultiplexLogger) Info(msg string) {
+	l.countLine(zerolog.InfoLevel)
 	for _, logger := range l.loggers {
 		logger.Info().Msg(msg)
 	}
 }
 
 func (l MultiplexLogger) Infof(format string, v ...interface{}) {
+	l.countLine(zerolog.InfoLevel)
 	for _, logger := range l.loggers {
 		logger.Info().Msgf(format, v...)
 	}
 }
 
 func (l MultiplexLogger) Warn(msg string) {
+	l.countLine(zerolog.WarnLevel)
 	for _, logger := range l.loggers {
 		logger.Warn().Msg(msg)
 	}
 }
 
 func (l MultiplexLogger) Warnf(format string, v ...interface{}) {
+	l.countLine(zerolog.WarnLevel)
 	for _, logger := range l.loggers {
 		logger.Warn().Msgf(format, v...)
 	}
 }
 
 func (l MultiplexLogger) Error(msg string) {
+	l.countLine(zerolog.ErrorLevel)
 	for _, logger := range l.loggers {
 		logger.Error().Msg(msg)
 	}
 }
 
 func (l MultiplexLogger) Errorf(format string, v ...interface{}) {
+	l.countLine(zerolog.ErrorLevel)
 	for _, logger := range l.loggers {
 		logger.Error().Msgf(format, v...)
 	}
 }
 
 func (l MultiplexLogger) Panic(msg string) {
+	l.countLine(zerolog.PanicLevel)
 	for _, logger := range l.loggers {
 		logger.WithLevel(zerolog.PanicLevel).Msg(msg) // doing it this way so that this doesn't actually kill the goroutine
 	}
 }
 
 func (l MultiplexLogger) Panicf(format string, v ...interface{}) {
+	l.countLine(zerolog.PanicLevel)
 	for _, logger := range l.loggers {
 		logger.WithLevel(zerolog.PanicLevel).Msgf(format, v...) // doing it this way so that this doesn't actually kill the goroutine
 	}
 }
 
 func (l MultiplexLogger) Fatal(msg string) {
+	l.countLine(zerolog.FatalLevel)
 	for _, logger := range l.loggers {
 		logger.WithLevel(zerolog.FatalLevel).Msg(msg) // doing it this way so that this doesn't actually kill the process
 	}
 }
 
 func (l MultiplexLogger) Fatalf(format string, v ...interface{}) {
+	l.countLine(zerolog.FatalLevel)
 	for _, logger := range l.loggers {
 		logger.WithLevel(zerolog.FatalLevel).Msgf(format, v...) // doing it this way so that this doesn't actually kill the process
 	}
@@ -0,0 +1,17 @@
+package log
+
+import "context"
+
+// RequestIDHeader carries the request ID between services
+const RequestIDHeader = "X-Request-ID"
+
+// WithRequestID stores the request ID in the context and adds it as a field to the context logger
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, "request-id", requestID)
+	return context.WithValue(ctx, "logger", Ctx(ctx).WithField("request-id", requestID))
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value("request-id").(string)
+	return requestID
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// atomicFloat is a float64 that can be updated from many goroutines without a lock
+type atomicFloat struct {
+	bits uint64
+}
+
+func (f *atomicFloat) Add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&f.bits)
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&f.bits, old, updated) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(value float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(value))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+type Counter struct {
+	value atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add panics on negative values, since counters only go up
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("counter cannot decrease")
+	}
+	c.value.Add(delta)
+}
+
+func (c *Counter) Value() float64 {
+	return c.value.Load()
+}
+
+type Gauge struct {
+	value atomicFloat
+}
+
+func (g *Gauge) Set(value float64) {
+	g.value.Set(value)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.value.Add(delta)
+}
+
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.value.Load()
+}
+
+type Histogram struct {
+	upperBounds []float64
+
+	mutex  sync.Mutex
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	bucket := sort.SearchFloat64s(h.upperBounds, value)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if bucket < len(h.counts) {
+		h.counts[bucket]++
+	}
+	h.count++
+	h.sum += value
+}
+
+type histogramSnapshot struct {
+	cumulativeCounts []uint64
+	count            uint64
+	sum              float64
+}
+
+func (h *Histogram) snapshot() histogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	cumulative := make([]uint64, len(h.counts))
+	var running uint64
+	for i, count := range h.counts {
+		running += count
+		cumulative[i] = running
+	}
+
+	return histogramSnapshot{cumulative, h.count, h.sum}
+}
+
+// vec holds one metric per combination of label values
+type vec[T any] struct {
+	name       string
+	help       string
+	labelNames []string
+	create     func() *T
+
+	mutex   sync.RWMutex
+	metrics map[string]*T
+	labels  map[string][]string
+}
+
+func newVec[T any](name string, help string, labelNames []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		create:     create,
+		metrics:    make(map[string]*T),
+		labels:     make(map[string][]string),
+	}
+}
+
+// With panics if the number of label values doesn't match the label names, since that's a programming error
+func (v *vec[T]) With(labelValues ...string) *T {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", v.name, len(v.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	v.mutex.RLock()
+	metric, exists := v.metrics[key]
+	v.mutex.RUnlock()
+	if exists {
+		return metric
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if metric, exists := v.metrics[key]; exists {
+		return metric
+	}
+
+	metric = v.create()
+	v.metrics[key] = metric
+	v.labels[key] = append([]string(nil), labelValues...)
+	return metric
+}
+
+type series[T any] struct {
+	labelValues []string
+	metric      *T
+}
+
+// each returns every series sorted by label values, so output is stable
+func (v *vec[T]) each() []series[T] {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	keys := make([]string, 0, len(v.metrics))
+	for key := range v.metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]series[T], len(keys))
+	for i, key := range keys {
+		all[i] = series[T]{v.labels[key], v.metrics[key]}
+	}
+	return all
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounterVec("requests_total", "Requests handled.", "route", "status")
+	inFlight := registry.NewGaugeVec("in_flight", "")
+	latency := registry.NewHistogramVec("latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+
+	requests.With("/a", "200").Inc()
+	requests.With("/a", "200").Add(2)
+	requests.With(`/b"\`, "500").Inc()
+	inFlight.With().Set(3)
+	latency.With("/a").Observe(0.05)
+	latency.With("/a").Observe(0.5)
+	latency.With("/a").Observe(5)
+
+	var builder strings.Builder
+	assert.NoError(t, registry.WriteText(&builder))
+
+	assert.Equal(t, `# TYPE in_flight gauge
+in_flight 3
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 5.55
+latency_seconds_count{route="/a"} 3
+# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{route="/a",status="200"} 3
+requests_total{route="/b\"\\",status="500"} 1
+`, builder.String())
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("count", "", "label")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				counter.With("value").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, float64(10000), counter.With("value").Value())
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("count", "").With().Inc()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE count counter\ncount 1\n", recorder.Body.String())
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("count", "", "a", "b")
+
+	assert.Panics(t, func() {
+		counter.With("only one")
+	})
+}
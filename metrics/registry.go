@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type collector interface {
+	metricName() string
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]collector),
+	}
+}
+
+var DefaultRegistry = NewRegistry()
+
+// register panics on duplicate names, since that's always a programming error caught at startup
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.collectors[c.metricName()]; exists {
+		panic("metric registered twice: " + c.metricName())
+	}
+
+	r.collectors[c.metricName()] = c
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	counters := &CounterVec{newVec(name, help, labelNames, func() *Counter {
+		return &Counter{}
+	})}
+	r.register(counters)
+	return counters
+}
+
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	gauges := &GaugeVec{newVec(name, help, labelNames, func() *Gauge {
+		return &Gauge{}
+	})}
+	r.register(gauges)
+	return gauges
+}
+
+// buckets are upper bounds in increasing order, nil for DefaultBuckets. The +Inf bucket is implied.
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	histograms := &HistogramVec{newVec(name, help, labelNames, func() *Histogram {
+		return &Histogram{
+			upperBounds: buckets,
+			counts:      make([]uint64, len(buckets)),
+		}
+	}), buckets}
+	r.register(histograms)
+	return histograms
+}
+
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return DefaultRegistry.NewCounterVec(name, help, labelNames...)
+}
+
+func NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	return DefaultRegistry.NewGaugeVec(name, help, labelNames...)
+}
+
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return DefaultRegistry.NewHistogramVec(name, help, buckets, labelNames...)
+}
+
+// WriteText writes every metric in the Prometheus text exposition format, sorted by name
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mutex.RUnlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buffered)
+	}
+
+	return buffered.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// Handler serves DefaultRegistry, usually at /metrics
+func Handler() http.Handler {
+	return DefaultRegistry.Handler()
+}
+
+func (v *CounterVec) metricName() string {
+	return v.name
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, "counter")
+	for _, s := range v.each() {
+		writeSample(w, v.name, v.labelNames, s.labelValues, "", "", s.metric.Value())
+	}
+}
+
+func (v *GaugeVec) metricName() string {
+	return v.name
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, "gauge")
+	for _, s := range v.each() {
+		writeSample(w, v.name, v.labelNames, s.labelValues, "", "", s.metric.Value())
+	}
+}
+
+func (v *HistogramVec) metricName() string {
+	return v.name
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, "histogram")
+	for _, s := range v.each() {
+		snapshot := s.metric.snapshot()
+
+		for i, upperBound := range v.buckets {
+			writeSample(w, v.name+"_bucket", v.labelNames, s.labelValues, "le", formatFloat(upperBound), float64(snapshot.cumulativeCounts[i]))
+		}
+		writeSample(w, v.name+"_bucket", v.labelNames, s.labelValues, "le", "+Inf", float64(snapshot.count))
+		writeSample(w, v.name+"_sum", v.labelNames, s.labelValues, "", "", snapshot.sum)
+		writeSample(w, v.name+"_count", v.labelNames, s.labelValues, "", "", float64(snapshot.count))
+	}
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, metricType string) {
+	if help != "" {
+		w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	}
+	w.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames []string, labelValues []string, extraName string, extraValue string, value float64) {
+	w.WriteString(name)
+
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(labelName + `="` + escapeLabelValue(labelValues[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -68,25 +68,31 @@ func (dao *DBDAO) Context() context.Context {
 
 func (dao *DBDAO) Exec(query string, args ...interface{}) (sql.Result, errors.Error) {
 	var myErr errors.Error
-	result, err := dao.sqlxer.ExecContext(dao.ctx, query, args...)
+	ctx, done := startQuery(dao.ctx, "Exec", query)
+	defer done(&myErr)
+	result, err := dao.sqlxer.ExecContext(ctx, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Exec", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Exec", query, args...)
 	}
 	return result, myErr
 }
 
 func (dao *DBDAO) Get(dest interface{}, query string, args ...interface{}) errors.Error {
 	var myErr errors.Error
-	err := dao.sqlxer.GetContext(dao.ctx, dest, query, args...)
+	ctx, done := startQuery(dao.ctx, "Get", query)
+	defer done(&myErr)
+	err := dao.sqlxer.GetContext(ctx, dest, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Get", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Get", query, args...)
 	}
 	return myErr
 }
 
 func (dao *DBDAO) NamedExec(query string, arg interface{}) (sql.Result, errors.Error) {
 	var myErr errors.Error
-	result, err := dao.sqlxer.NamedExecContext(dao.ctx, query, arg)
+	ctx, done := startQuery(dao.ctx, "NamedExec", query)
+	defer done(&myErr)
+	result, err := dao.sqlxer.NamedExecContext(ctx, query, arg)
 	if err != nil {
 		myErr = errors.WrapQueryError(err, "Error running NamedExec", query, arg)
 	}
@@ -95,18 +101,22 @@ func (dao *DBDAO) NamedExec(query string, arg interface{}) (sql.Result, errors.E
 
 func (dao *DBDAO) PrepareNamed(query string) (*sqlx.NamedStmt, errors.Error) {
 	var myErr errors.Error
-	namedStmnt, err := dao.sqlxer.PrepareNamedContext(dao.ctx, query)
+	ctx, done := startQuery(dao.ctx, "PrepareNamed", query)
+	defer done(&myErr)
+	namedStmnt, err := dao.sqlxer.PrepareNamedContext(ctx, query)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running PrepareNamed", query, nil)
+		myErr = errors.WrapQueryError(err, "Error running PrepareNamed", query)
 	}
 	return namedStmnt, myErr
 }
 
 func (dao *DBDAO) Preparex(query string) (*sqlx.Stmt, errors.Error) {
 	var myErr errors.Error
-	stmnt, err := dao.sqlxer.PreparexContext(dao.ctx, query)
+	ctx, done := startQuery(dao.ctx, "Preparex", query)
+	defer done(&myErr)
+	stmnt, err := dao.sqlxer.PreparexContext(ctx, query)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Preparex", query, nil)
+		myErr = errors.WrapQueryError(err, "Error running Preparex", query)
 	}
 	return stmnt, myErr
 }
@@ -117,9 +127,11 @@ func (dao *DBDAO) Rebind(query string) string {
 
 func (dao *DBDAO) Select(dest interface{}, query string, args ...interface{}) errors.Error {
 	var myErr errors.Error
-	err := dao.sqlxer.SelectContext(dao.ctx, dest, query, args...)
+	ctx, done := startQuery(dao.ctx, "Select", query)
+	defer done(&myErr)
+	err := dao.sqlxer.SelectContext(ctx, dest, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Select", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Select", query, args...)
 	}
 	return myErr
 }
@@ -142,9 +154,9 @@ func NewTXDAO(db *sqlx.DB, ctx context.Context) (*TxDAO, errors.Error) {
 
 	txLogger := log.Ctx(ctx).WithField("repo-dao-id", getNextDaoId())
 
-	tx, err := db.Beginx()
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
-		myErr := errors.Wrap(err, "Error beginning transaction")
+		myErr := errors.WrapDBError(err, "Error beginning transaction")
 		return nil, myErr
 	}
 
@@ -163,25 +175,31 @@ func (dao *TxDAO) Context() context.Context {
 
 func (dao *TxDAO) Exec(query string, args ...interface{}) (sql.Result, errors.Error) {
 	var myErr errors.Error
-	result, err := dao.sqlxer.ExecContext(dao.ctx, query, args...)
+	ctx, done := startQuery(dao.ctx, "Exec", query)
+	defer done(&myErr)
+	result, err := dao.sqlxer.ExecContext(ctx, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Exec", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Exec", query, args...)
 	}
 	return result, myErr
 }
 
 func (dao *TxDAO) Get(dest interface{}, query string, args ...interface{}) errors.Error {
 	var myErr errors.Error
-	err := dao.sqlxer.GetContext(dao.ctx, dest, query, args...)
+	ctx, done := startQuery(dao.ctx, "Get", query)
+	defer done(&myErr)
+	err := dao.sqlxer.GetContext(ctx, dest, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Get", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Get", query, args...)
 	}
 	return myErr
 }
 
 func (dao *TxDAO) NamedExec(query string, arg interface{}) (sql.Result, errors.Error) {
 	var myErr errors.Error
-	result, err := dao.sqlxer.NamedExecContext(dao.ctx, query, arg)
+	ctx, done := startQuery(dao.ctx, "NamedExec", query)
+	defer done(&myErr)
+	result, err := dao.sqlxer.NamedExecContext(ctx, query, arg)
 	if err != nil {
 		myErr = errors.WrapQueryError(err, "Error running NamedExec", query, arg)
 	}
@@ -190,18 +208,22 @@ func (dao *TxDAO) NamedExec(query string, arg interface{}) (sql.Result, errors.E
 
 func (dao *TxDAO) PrepareNamed(query string) (*sqlx.NamedStmt, errors.Error) {
 	var myErr errors.Error
-	namedStmnt, err := dao.sqlxer.PrepareNamedContext(dao.ctx, query)
+	ctx, done := startQuery(dao.ctx, "PrepareNamed", query)
+	defer done(&myErr)
+	namedStmnt, err := dao.sqlxer.PrepareNamedContext(ctx, query)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running PrepareNamed", query, nil)
+		myErr = errors.WrapQueryError(err, "Error running PrepareNamed", query)
 	}
 	return namedStmnt, myErr
 }
 
 func (dao *TxDAO) Preparex(query string) (*sqlx.Stmt, errors.Error) {
 	var myErr errors.Error
-	stmnt, err := dao.sqlxer.PreparexContext(dao.ctx, query)
+	ctx, done := startQuery(dao.ctx, "Preparex", query)
+	defer done(&myErr)
+	stmnt, err := dao.sqlxer.PreparexContext(ctx, query)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Preparex", query, nil)
+		myErr = errors.WrapQueryError(err, "Error running Preparex", query)
 	}
 	return stmnt, myErr
 }
@@ -212,9 +234,11 @@ func (dao *TxDAO) Rebind(query string) string {
 
 func (dao *TxDAO) Select(dest interface{}, query string, args ...interface{}) errors.Error {
 	var myErr errors.Error
-	err := dao.sqlxer.SelectContext(dao.ctx, dest, query, args...)
+	ctx, done := startQuery(dao.ctx, "Select", query)
+	defer done(&myErr)
+	err := dao.sqlxer.SelectContext(ctx, dest, query, args...)
 	if err != nil {
-		myErr = errors.WrapQueryError(err, "Error running Select", query, args)
+		myErr = errors.WrapQueryError(err, "Error running Select", query, args...)
 	}
 	return myErr
 }
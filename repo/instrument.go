@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/metrics"
+	"github.com/sjohna/go-server-common/tracing"
+	"time"
+)
+
+var queriesTotal = metrics.NewCounterVec("db_queries_total", "Database queries run, by DAO operation and result.", "operation", "result")
+var queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", "Time taken by database queries, by DAO operation.", nil, "operation")
+var transactionsTotal = metrics.NewCounterVec("db_transactions_total", "Transactions finished, by result.", "result")
+
+// startQuery starts the span and timer for a DAO operation. The returned function is meant to be deferred, so err is
+// read once the method returns.
+func startQuery(ctx context.Context, operation string, query string) (context.Context, func(err *errors.Error)) {
+	ctx, span := tracing.Start(ctx, "db."+operation, tracing.KindClient)
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.statement", query)
+	start := time.Now()
+
+	return ctx, func(err *errors.Error) {
+		result := "ok"
+		if *err != nil {
+			result = "error"
+			span.SetError(*err)
+		}
+
+		queriesTotal.With(operation, result).Inc()
+		queryDuration.With(operation).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
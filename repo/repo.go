@@ -5,6 +5,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/sjohna/go-server-common/errors"
 	"github.com/sjohna/go-server-common/log"
+	"github.com/sjohna/go-server-common/tracing"
 )
 
 type Repo struct {
@@ -16,6 +17,17 @@ func (repo *Repo) NonTx(ctx context.Context) *DBDAO {
 }
 
 func (repo *Repo) SerializableTx(ctx context.Context, transactionFunc func(*TxDAO) errors.Error) errors.Error {
+	ctx, span := tracing.Start(ctx, "db.transaction", tracing.KindInternal)
+	span.SetAttribute("db.isolation", "serializable")
+
+	err := repo.serializableTx(ctx, transactionFunc)
+
+	span.SetError(err)
+	span.End()
+	return err
+}
+
+func (repo *Repo) serializableTx(ctx context.Context, transactionFunc func(*TxDAO) errors.Error) errors.Error {
 	dao, err := NewTXDAO(repo.DB, ctx)
 	if err != nil {
 
@@ -24,6 +36,7 @@ func (repo *Repo) SerializableTx(ctx context.Context, transactionFunc func(*TxDA
 
 	_, err = dao.Exec("set transaction isolation level serializable")
 	if err != nil {
+		transactionsTotal.With("rollback").Inc()
 		if rollbackErr := dao.sqlxer.Rollback(); rollbackErr != nil {
 			log.Ctx(ctx).WithError(errors.WrapDBError(rollbackErr, "failed to rollback transaction")).Error("Failed to rollback transaction!!!!")
 		}
@@ -35,6 +48,7 @@ func (repo *Repo) SerializableTx(ctx context.Context, transactionFunc func(*TxDA
 		commitErr := dao.sqlxer.Commit()
 		if commitErr != nil {
 			wrappedCommitErr := errors.WrapDBError(commitErr, "failed to commit transaction")
+			transactionsTotal.With("commit_failed").Inc()
 
 			rollbackError := dao.sqlxer.Rollback()
 			if rollbackError != nil {
@@ -43,7 +57,9 @@ func (repo *Repo) SerializableTx(ctx context.Context, transactionFunc func(*TxDA
 
 			return wrappedCommitErr
 		}
+		transactionsTotal.With("commit").Inc()
 	} else {
+		transactionsTotal.With("rollback").Inc()
 		rollbackError := dao.sqlxer.Rollback()
 		if rollbackError != nil {
 			log.Ctx(dao.ctx).WithError(errors.WrapDBError(rollbackError, "failed to rollback transaction")).Error("Failed to rollback transaction that returned an error!!!!")
@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"math/rand"
+	"time"
+)
+
+// Policy is how operations are retried. Zero fields other than Jitter are taken from DefaultPolicy.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64 // fraction of each backoff that is randomized, between 0 and 1
+}
+
+var DefaultPolicy = Policy{
+	MaxAttempts:    5,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Do runs operation until it succeeds, returns an error that isn't retryable, or runs out of attempts. The last error
+// is returned if all attempts fail or the context is done while waiting to retry.
+func Do(ctx context.Context, policy Policy, operation func() errors.Error) errors.Error {
+	logger := log.Ctx(ctx)
+	policy = policy.withDefaults()
+	backoff := policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			if attempt > 1 {
+				logger.WithField("retry-attempt", attempt).Debug("Operation succeeded after retrying")
+			}
+			return nil
+		}
+
+		if !err.Retryable() {
+			return err
+		}
+
+		if attempt >= policy.MaxAttempts {
+			logger.WithError(err).WithField("retry-attempt", attempt).Warn("Giving up on retryable operation")
+			return err
+		}
+
+		wait := policy.jittered(backoff)
+		logger.WithError(err).WithFields(log.Fields{
+			"retry-attempt": attempt,
+			"retry-backoff": wait.String(),
+		}).Info("Retrying operation after retryable error")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.WithError(err).WithField("retry-attempt", attempt).Info("Context done while waiting to retry operation")
+			return err
+		case <-timer.C:
+		}
+
+		backoff = policy.next(backoff)
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultPolicy.MaxBackoff
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = DefaultPolicy.Multiplier
+	}
+
+	return p
+}
+
+func (p Policy) next(backoff time.Duration) time.Duration {
+	next := time.Duration(float64(backoff) * p.Multiplier)
+	if next > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return next
+}
+
+func (p Policy) jittered(backoff time.Duration) time.Duration {
+	if p.Jitter <= 0 || backoff <= 0 {
+		return backoff
+	}
+
+	jitter := float64(backoff) * p.Jitter
+	return backoff - time.Duration(jitter) + time.Duration(rand.Float64()*2*jitter)
+}
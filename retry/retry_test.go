@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/stretchr/testify/assert"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     2 * time.Millisecond,
+	Multiplier:     2,
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", log.NewMultiplexLogger(nil))
+
+	t.Run("Retries temporary errors until success", func(t *testing.T) {
+		attempts := 0
+		err := Do(ctx, testPolicy, func() errors.Error {
+			attempts++
+			if attempts < 3 {
+				return errors.Wrap(fmt.Errorf("read: %w", syscall.ECONNRESET), "connection reset")
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Stops after max attempts", func(t *testing.T) {
+		attempts := 0
+		err := Do(ctx, testPolicy, func() errors.Error {
+			attempts++
+			return errors.NewThirdPartyStatus(503, "unavailable")
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Doesn't retry permanent errors", func(t *testing.T) {
+		attempts := 0
+		err := Do(ctx, testPolicy, func() errors.Error {
+			attempts++
+			return errors.New("permanent")
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Stops when context is done", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		attempts := 0
+		err := Do(canceledCtx, Policy{MaxAttempts: 10, InitialBackoff: time.Hour}, func() errors.Error {
+			attempts++
+			return errors.NewThirdPartyStatus(503, "unavailable")
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	policy := Policy{MaxAttempts: 2}.withDefaults()
+	assert.Equal(t, 2, policy.MaxAttempts)
+	assert.Equal(t, DefaultPolicy.InitialBackoff, policy.InitialBackoff)
+	assert.Equal(t, 2*DefaultPolicy.InitialBackoff, policy.next(policy.InitialBackoff))
+	assert.Equal(t, DefaultPolicy.MaxBackoff, policy.next(time.Hour))
+	assert.Equal(t, 0.0, policy.Jitter)
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/sjohna/go-server-common/log"
 	"net/http"
 	"runtime"
-	"sync"
 )
 
 func main() {
@@ -67,19 +66,17 @@ func testError() errors.Error {
 }
 
 func testErrorInGoRoutine() errors.Error {
-	wg := sync.WaitGroup{}
+	group, _ := errors.NewGroup(context.Background())
 
-	wg.Add(1)
+	group.Go(func(ctx context.Context) errors.Error {
+		return errors.New("error from goroutine")
+	})
 
-	var err errors.Error
+	group.Go(func(ctx context.Context) errors.Error {
+		panic("panic in goroutine")
+	})
 
-	go func() {
-		defer wg.Done()
-		err = errors.New("error from goroutine")
-	}()
-
-	wg.Wait()
-	return err
+	return group.WaitAll()
 }
 
 func testQueryError() errors.Error {
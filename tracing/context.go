@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+const flagSampled = 0x01
+
+// SpanContext is the part of a span that crosses process boundaries, as defined by W3C trace context
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// Traceparent formats the version 00 traceparent header value
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceparent accepts any version but 0xff, ignoring fields after the flags for future versions as the spec asks
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+
+	version, versionOk := decodeHex(parts[0], 1)
+	if !versionOk || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	traceID, traceIDOk := decodeHex(parts[1], 16)
+	spanID, spanIDOk := decodeHex(parts[2], 8)
+	flags, flagsOk := decodeHex(parts[3], 1)
+	if !traceIDOk || !spanIDOk || !flagsOk {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+
+	return sc, sc.IsValid()
+}
+
+// Extract reads the span context of the caller from request headers
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, valid := ParseTraceparent(header.Get(TraceparentHeader))
+	if !valid {
+		return SpanContext{}, false
+	}
+
+	sc.TraceState = header.Get(TracestateHeader)
+	return sc, true
+}
+
+// Inject writes the span context into outgoing request headers
+func Inject(sc SpanContext, header http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// only lowercase hex is valid in traceparent
+func decodeHex(value string, length int) ([]byte, bool) {
+	if len(value) != length*2 || strings.ToLower(value) != value {
+		return nil, false
+	}
+
+	decoded, err := hex.DecodeString(value)
+	return decoded, err == nil
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,250 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/sjohna/go-server-common/metrics"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) errors.Error
+}
+
+var exporter Exporter
+var ownedBatcher *Batcher // the batcher SetExporter wrapped the exporter in, which it stops when replaced
+var exporterMutex sync.RWMutex
+
+const (
+	defaultBatchSize     = 512
+	defaultBatchInterval = 5 * time.Second
+	queuedBatches        = 4 // how many batches a Batcher holds while the exporter is slow or failing
+)
+
+var spansDropped = metrics.NewCounterVec("tracing_spans_dropped_total", "Spans dropped because the exporter couldn't keep up or failed.")
+
+// SetExporter sets where spans go, dropping them until it's called. Exporters other than a *Batcher are wrapped in one
+// with default settings, so exporting never happens on the request path in Span.End. Call Shutdown before exiting to
+// export what's still buffered.
+func SetExporter(e Exporter) {
+	var owned *Batcher
+	if _, isBatcher := e.(*Batcher); e != nil && !isBatcher {
+		owned = NewBatcher(e, defaultBatchSize, defaultBatchInterval)
+		e = owned
+	}
+
+	exporterMutex.Lock()
+	replaced := ownedBatcher
+	exporter = e
+	ownedBatcher = owned
+	exporterMutex.Unlock()
+
+	if replaced != nil {
+		go func() {
+			if err := replaced.Shutdown(context.Background()); err != nil {
+				log.General.WithError(err).Warn("Failed to export spans of replaced exporter")
+			}
+		}()
+	}
+}
+
+// Shutdown stops the exporter set with SetExporter after exporting the spans it's still holding. Register it as a
+// shutdown hook.
+func Shutdown(ctx context.Context) errors.Error {
+	exporterMutex.RLock()
+	e := exporter
+	exporterMutex.RUnlock()
+
+	if batcher, isBatcher := e.(*Batcher); isBatcher {
+		return batcher.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+func export(data SpanData) {
+	exporterMutex.RLock()
+	e := exporter
+	exporterMutex.RUnlock()
+
+	if e == nil {
+		return
+	}
+
+	if err := e.Export(context.Background(), []SpanData{data}); err != nil {
+		log.General.WithError(err).Warn("Failed to export span")
+	}
+}
+
+// WriterExporter writes each span as a line of JSON
+type WriterExporter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{writer: w}
+}
+
+func NewStdoutExporter() *WriterExporter {
+	return NewWriterExporter(os.Stdout)
+}
+
+// NewFileExporter rotates files the same way the application logs do
+func NewFileExporter(filePath string) *WriterExporter {
+	return NewWriterExporter(&lumberjack.Logger{
+		Filename:   filePath,
+		MaxSize:    100,
+		MaxBackups: 10,
+		MaxAge:     36500, // 100 years
+		Compress:   false,
+	})
+}
+
+func (e *WriterExporter) Export(ctx context.Context, spans []SpanData) errors.Error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	encoder := json.NewEncoder(e.writer)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return errors.Wrap(err, "Failed to write span")
+		}
+	}
+
+	return nil
+}
+
+// Batcher buffers spans and hands them to another exporter in batches, either when the batch is full or every
+// interval. Shutdown flushes whatever is left, so register it as a shutdown hook. It holds at most a few batches, so
+// while the exporter is slow or down, newer spans are dropped and counted in tracing_spans_dropped_total.
+type Batcher struct {
+	exporter     Exporter
+	maxBatchSize int
+	maxQueued    int
+
+	mutex    sync.Mutex
+	pending  []SpanData
+	stopped  bool
+	flushes  chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewBatcher uses defaults for a maxBatchSize or interval that isn't positive
+func NewBatcher(exporter Exporter, maxBatchSize int, interval time.Duration) *Batcher {
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+
+	b := &Batcher{
+		exporter:     exporter,
+		maxBatchSize: maxBatchSize,
+		maxQueued:    maxBatchSize * queuedBatches,
+		flushes:      make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go b.run(interval)
+	return b
+}
+
+// Export only buffers the spans. Spans exported after Shutdown are dropped.
+func (b *Batcher) Export(ctx context.Context, spans []SpanData) errors.Error {
+	b.mutex.Lock()
+	if b.stopped {
+		b.mutex.Unlock()
+		return nil
+	}
+	dropped := 0
+	if room := b.maxQueued - len(b.pending); len(spans) > room {
+		dropped = len(spans) - room
+		spans = spans[:room]
+	}
+	b.pending = append(b.pending, spans...)
+	full := len(b.pending) >= b.maxBatchSize
+	b.mutex.Unlock()
+
+	if dropped > 0 {
+		spansDropped.With().Add(float64(dropped))
+	}
+
+	if full {
+		select {
+		case b.flushes <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Shutdown can be called more than once, and later calls only wait for the first to finish stopping the batcher
+func (b *Batcher) Shutdown(ctx context.Context) errors.Error {
+	b.stopOnce.Do(func() {
+		b.mutex.Lock()
+		b.stopped = true
+		b.mutex.Unlock()
+
+		close(b.stop)
+	})
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "Timed out waiting for span batcher to stop")
+	}
+
+	return b.flush(ctx)
+}
+
+func (b *Batcher) run(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.flushes:
+		}
+
+		if err := b.flush(context.Background()); err != nil {
+			log.General.WithError(err).Warn("Failed to export span batch")
+		}
+	}
+}
+
+func (b *Batcher) flush(ctx context.Context) errors.Error {
+	b.mutex.Lock()
+	spans := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+
+	for len(spans) > 0 {
+		batch := spans[:min(len(spans), b.maxBatchSize)]
+		spans = spans[len(batch):]
+
+		// the exporter is failing, so don't wait on it again for the rest
+		if err := b.exporter.Export(ctx, batch); err != nil {
+			spansDropped.With().Add(float64(len(batch) + len(spans)))
+			return err
+		}
+	}
+
+	return nil
+}
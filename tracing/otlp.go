@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sjohna/go-server-common/errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLPExporter sends spans to a collector with OTLP's JSON encoding over HTTP. Wrap it in a Batcher, since it makes a
+// request per export. It deliberately doesn't use httpclient, which would trace the export requests themselves.
+type OTLPExporter struct {
+	ServiceName string
+	Endpoint    string // full URL of the traces endpoint, e.g. http://localhost:4318/v1/traces
+	Headers     http.Header
+	HTTPClient  *http.Client
+}
+
+func NewOTLPExporter(serviceName string, endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		ServiceName: serviceName,
+		Endpoint:    endpoint,
+		Headers:     http.Header{},
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64s are strings in OTLP JSON
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) errors.Error {
+	resourceSpans := otlpResourceSpans{}
+	resourceSpans.Resource.Attributes = []otlpAttribute{attribute("service.name", e.ServiceName)}
+
+	scopeSpans := otlpScopeSpans{Spans: make([]otlpSpan, len(spans))}
+	scopeSpans.Scope.Name = "github.com/sjohna/go-server-common/tracing"
+
+	for i, span := range spans {
+		scopeSpans.Spans[i] = toOTLP(span)
+	}
+
+	resourceSpans.ScopeSpans = []otlpScopeSpans{scopeSpans}
+
+	body, err := json.Marshal(otlpRequest{[]otlpResourceSpans{resourceSpans}})
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal OTLP request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create OTLP request")
+	}
+
+	for key, values := range e.Headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return errors.WrapThirdParty(err, "Failed to send spans to OTLP collector")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.NewThirdPartyStatus(resp.StatusCode, "OTLP collector responded with "+resp.Status)
+	}
+
+	return nil
+}
+
+func toOTLP(span SpanData) otlpSpan {
+	keys := make([]string, 0, len(span.Attributes))
+	for key := range span.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]otlpAttribute, len(keys))
+	for i, key := range keys {
+		attributes[i] = attribute(key, span.Attributes[key])
+	}
+
+	status := otlpStatus{Code: 1} // ok
+	if span.Error {
+		status = otlpStatus{Code: 2, Message: span.StatusMessage}
+	}
+
+	return otlpSpan{
+		TraceID:           span.TraceID,
+		SpanID:            span.SpanID,
+		ParentSpanID:      span.ParentSpanID,
+		Name:              span.Name,
+		Kind:              otlpKind(span.Kind),
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	}
+}
+
+// OTLP numbers kinds from 1, with unspecified as 0
+func otlpKind(kind Kind) int {
+	switch kind {
+	case KindServer:
+		return 2
+	case KindClient:
+		return 3
+	}
+
+	return 1
+}
+
+func attribute(key string, value interface{}) otlpAttribute {
+	var otlp otlpValue
+
+	switch v := value.(type) {
+	case string:
+		otlp.StringValue = &v
+	case bool:
+		otlp.BoolValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		otlp.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		otlp.IntValue = &s
+	case float64:
+		otlp.DoubleValue = &v
+	default:
+		s := fmt.Sprint(v)
+		otlp.StringValue = &s
+	}
+
+	return otlpAttribute{key, otlp}
+}
@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"sync"
+	"time"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindServer
+	KindClient
+)
+
+// SpanData is a finished span as handed to exporters
+type SpanData struct {
+	Name          string                 `json:"name"`
+	Kind          Kind                   `json:"kind"`
+	TraceID       string                 `json:"traceId"`
+	SpanID        string                 `json:"spanId"`
+	ParentSpanID  string                 `json:"parentSpanId,omitempty"`
+	Start         time.Time              `json:"start"`
+	End           time.Time              `json:"end"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Error         bool                   `json:"error,omitempty"`
+	StatusMessage string                 `json:"statusMessage,omitempty"`
+}
+
+type Span struct {
+	name        string
+	kind        Kind
+	spanContext SpanContext
+	parentID    SpanID
+	start       time.Time
+
+	mutex         sync.Mutex
+	ended         bool
+	attributes    map[string]interface{}
+	failed        bool
+	statusMessage string
+}
+
+// Start begins a span that is a child of the span in ctx, or a new trace if there isn't one. The returned context
+// carries the span, and its logger has trace_id and span_id fields so every log line can be tied to the trace. The
+// span_id is that of the first span in the process, not the innermost one.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent, hasParent := ctx.Value("span-context").(SpanContext)
+	return start(ctx, name, kind, parent, hasParent)
+}
+
+// StartRemote begins a span whose parent is in another process, usually from Extract. An invalid parent starts a new
+// trace.
+func StartRemote(ctx context.Context, name string, kind Kind, parent SpanContext) (context.Context, *Span) {
+	return start(ctx, name, kind, parent, parent.IsValid())
+}
+
+func start(ctx context.Context, name string, kind Kind, parent SpanContext, hasParent bool) (context.Context, *Span) {
+	span := &Span{
+		name:  name,
+		kind:  kind,
+		start: time.Now(),
+	}
+
+	if hasParent {
+		span.spanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     newSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+		span.parentID = parent.SpanID
+	} else {
+		span.spanContext = SpanContext{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+			Flags:   flagSampled,
+		}
+	}
+
+	// the logger only gets the IDs of the first span in this process, usually the request's server span. Loggers can't
+	// replace fields, so adding them again for child spans would repeat the keys on every line.
+	if _, inSpan := ctx.Value("span-context").(SpanContext); !inSpan {
+		ctx = context.WithValue(ctx, "logger", log.Ctx(ctx).WithFields(log.Fields{
+			"trace_id": span.spanContext.TraceID.String(),
+			"span_id":  span.spanContext.SpanID.String(),
+		}))
+	}
+	ctx = context.WithValue(ctx, "span-context", span.spanContext)
+
+	return ctx, span
+}
+
+// SpanContextFromContext returns the context of the current span, for propagating it to other services
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, exists := ctx.Value("span-context").(SpanContext)
+	return sc, exists
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.spanContext
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed. Errors that aren't the service's fault, like bad input, are only recorded as an
+// attribute.
+func (s *Span) SetError(err errors.Error) {
+	if err == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes["error.message"] = err.Error()
+
+	if err.Internal() {
+		s.failed = true
+		s.statusMessage = err.Error()
+	}
+}
+
+// End finishes the span and exports it if the trace is sampled. Calling it more than once does nothing.
+func (s *Span) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+
+	data := SpanData{
+		Name:          s.name,
+		Kind:          s.kind,
+		TraceID:       s.spanContext.TraceID.String(),
+		SpanID:        s.spanContext.SpanID.String(),
+		Start:         s.start,
+		End:           time.Now(),
+		Attributes:    s.attributes,
+		Error:         s.failed,
+		StatusMessage: s.statusMessage,
+	}
+	s.mutex.Unlock()
+
+	if s.parentID.IsValid() {
+		data.ParentSpanID = s.parentID.String()
+	}
+
+	if s.spanContext.Sampled() {
+		export(data)
+	}
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"github.com/sjohna/go-server-common/errors"
+	"github.com/sjohna/go-server-common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []SpanData) errors.Error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, valid := ParseTraceparent(value)
+	require.True(t, valid)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled())
+	assert.Equal(t, value, sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, valid := ParseTraceparent(invalid)
+		assert.False(t, valid, invalid)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	incoming := http.Header{}
+	incoming.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set(TracestateHeader, "vendor=value")
+
+	remote, valid := Extract(incoming)
+	require.True(t, valid)
+
+	_, span := StartRemote(context.Background(), "server", KindServer, remote)
+
+	outgoing := http.Header{}
+	Inject(span.SpanContext(), outgoing)
+
+	sc, valid := Extract(outgoing)
+	require.True(t, valid)
+	assert.Equal(t, remote.TraceID, sc.TraceID)
+	assert.NotEqual(t, remote.SpanID, sc.SpanID)
+	assert.Equal(t, "vendor=value", sc.TraceState)
+}
+
+func TestChildSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	ctx, parent := Start(context.Background(), "parent", KindServer)
+	childCtx, child := Start(ctx, "child", KindClient)
+
+	child.SetError(errors.New("Query failed"))
+	child.End()
+	parent.SetError(errors.NewInput("Bad request"))
+	parent.End()
+	parent.End()
+
+	current, exists := SpanContextFromContext(childCtx)
+	require.True(t, exists)
+	assert.Equal(t, child.SpanContext(), current)
+
+	assert.Empty(t, exporter.spans, "spans are exported in the background")
+	require.Nil(t, Shutdown(context.Background()))
+	require.Len(t, exporter.spans, 2)
+	childData, parentData := exporter.spans[0], exporter.spans[1]
+
+	assert.Equal(t, parentData.TraceID, childData.TraceID)
+	assert.Equal(t, parentData.SpanID, childData.ParentSpanID)
+	assert.Empty(t, parentData.ParentSpanID)
+
+	assert.True(t, childData.Error)
+	assert.False(t, parentData.Error, "input errors aren't span failures")
+	assert.Equal(t, "Bad request", parentData.Attributes["error.message"])
+}
+
+func TestSpanLogFields(t *testing.T) {
+	var logged bytes.Buffer
+	ctx := context.WithValue(context.Background(), "logger", log.NewMultiplexLogger([]zerolog.Logger{zerolog.New(&logged)}))
+
+	ctx, parent := Start(ctx, "parent", KindServer)
+	defer parent.End()
+	childCtx, child := Start(ctx, "child", KindInternal)
+	defer child.End()
+
+	log.Ctx(childCtx).Info("in child")
+	assert.Equal(t, 1, strings.Count(logged.String(), `"trace_id"`))
+	assert.Equal(t, 1, strings.Count(logged.String(), `"span_id"`))
+	assert.Contains(t, logged.String(), `"span_id":"`+parent.SpanContext().SpanID.String()+`"`)
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	exporter := &recordingExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	remote, valid := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.True(t, valid)
+
+	_, span := StartRemote(context.Background(), "server", KindServer, remote)
+	span.End()
+
+	require.Nil(t, Shutdown(context.Background()))
+	assert.Empty(t, exporter.spans)
+}
+
+func TestBatcher(t *testing.T) {
+	exporter := &recordingExporter{}
+	batcher := NewBatcher(exporter, 2, 0)
+
+	assert.Nil(t, batcher.Export(context.Background(), []SpanData{{Name: "first"}}))
+	assert.Nil(t, batcher.Export(context.Background(), []SpanData{{Name: "second"}}))
+	assert.Eventually(t, func() bool {
+		exporter.mutex.Lock()
+		defer exporter.mutex.Unlock()
+		return len(exporter.spans) == 2
+	}, time.Second, time.Millisecond, "a full batch is exported without waiting for the interval")
+
+	assert.Nil(t, batcher.Export(context.Background(), []SpanData{{Name: "third"}}))
+	assert.Nil(t, batcher.Shutdown(context.Background()))
+	assert.Nil(t, batcher.Shutdown(context.Background()))
+	assert.Nil(t, batcher.Export(context.Background(), []SpanData{{Name: "dropped"}}))
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	assert.Len(t, exporter.spans, 3)
+}
+
+// blockingExporter holds up every export until release is closed, like a collector that's down
+type blockingExporter struct {
+	recordingExporter
+	release chan struct{}
+	batches []int
+}
+
+func (e *blockingExporter) Export(ctx context.Context, spans []SpanData) errors.Error {
+	<-e.release
+
+	e.mutex.Lock()
+	e.batches = append(e.batches, len(spans))
+	e.mutex.Unlock()
+
+	return e.recordingExporter.Export(ctx, spans)
+}
+
+func TestBatcherLimits(t *testing.T) {
+	exporter := &blockingExporter{release: make(chan struct{})}
+	batcher := NewBatcher(exporter, 2, time.Hour)
+	dropped := spansDropped.With()
+	before := dropped.Value()
+
+	// the first full batch is taken for export, which then hangs
+	assert.Nil(t, batcher.Export(context.Background(), make([]SpanData, 2)))
+	assert.Eventually(t, func() bool {
+		batcher.mutex.Lock()
+		defer batcher.mutex.Unlock()
+		return len(batcher.pending) == 0
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, batcher.Export(context.Background(), make([]SpanData, 10)))
+	assert.Equal(t, 2.0, dropped.Value()-before, "only queuedBatches batches are held")
+
+	close(exporter.release)
+	require.Nil(t, batcher.Shutdown(context.Background()))
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	assert.Len(t, exporter.spans, 10)
+	for _, size := range exporter.batches {
+		assert.LessOrEqual(t, size, 2, "the backlog is exported in batches")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received map[string]interface{}
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter("test-service", server.URL+"/v1/traces")
+	exporter.Headers = http.Header{"Authorization": []string{"Bearer token"}}
+
+	_, span := Start(context.Background(), "operation", KindServer)
+	span.SetAttribute("http.status_code", 200)
+
+	err := exporter.Export(context.Background(), []SpanData{{
+		Name:       "operation",
+		Kind:       KindServer,
+		TraceID:    span.SpanContext().TraceID.String(),
+		SpanID:     span.SpanContext().SpanID.String(),
+		Attributes: map[string]interface{}{"http.status_code": 200},
+	}})
+	require.Nil(t, err)
+
+	assert.Equal(t, "Bearer token", authorization)
+
+	resourceSpans := received["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	serviceName := resourceSpans["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "service.name", serviceName["key"])
+
+	spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 1)
+	assert.Equal(t, "operation", spans[0].(map[string]interface{})["name"])
+	assert.Equal(t, span.SpanContext().TraceID.String(), spans[0].(map[string]interface{})["traceId"])
+}
+
+func TestOTLPExporterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewOTLPExporter("test-service", server.URL).Export(context.Background(), []SpanData{{Name: "operation"}})
+	require.NotNil(t, err)
+	assert.True(t, err.Temporary())
+}